@@ -0,0 +1,63 @@
+package board
+
+import "math/bits"
+
+// Bitboard is a set of squares, with one bit for each square from a1 as bit
+// 0 to h8 as bit 63.
+type Bitboard uint64
+
+// Has reports whether the set contains the given 0x88 square.
+func (bb Bitboard) Has(square int) bool {
+	return LegalSquareIndex(square) && uint64(bb)&squareBB(square) != 0
+}
+
+// Count returns the number of squares in the set.
+func (bb Bitboard) Count() int {
+	return bits.OnesCount64(uint64(bb))
+}
+
+// Squares returns the 0x88 squares in the set, from a1 up to h8.
+func (bb Bitboard) Squares() []int {
+	result := make([]int, 0, bb.Count())
+	for remaining := uint64(bb); remaining != 0; {
+		result = append(result, popSquare(&remaining))
+	}
+	return result
+}
+
+// AttackersTo returns the pieces of both colours attacking the given 0x88
+// square. Pawns only attack diagonally, and a piece behind another on the
+// same line doesn't count until the one in front has moved.
+func AttackersTo(b *Board, square int) Bitboard {
+	occupied := b.occupied()
+	return Bitboard(attackersTo(b, square, WHITE, occupied) | attackersTo(b, square, BLACK, occupied))
+}
+
+// AttackMap returns the number of pieces of the given colour attacking each
+// square, indexed by 0x88 square.
+func AttackMap(b *Board, colour int) [128]int {
+	var result [128]int
+	occupied := b.occupied()
+	for pieces := b.colourBitboards[colour>>3]; pieces != 0; {
+		square := popSquare(&pieces)
+		for attacks := pieceAttacks(b.squares[square], square, occupied); attacks != 0; {
+			result[popSquare(&attacks)]++
+		}
+	}
+	return result
+}
+
+// Pin is a piece that can't move off the line between its king and an
+// opposing bishop, rook or queen without exposing the king to check.
+type Pin struct {
+	Pinned int // The 0x88 square of the pinned piece.
+	Pinner int // The 0x88 square of the piece pinning it.
+}
+
+// Pins returns the pieces of the given colour which are pinned to their
+// king, along with the pieces pinning them. Only pins against the king are
+// found, not pieces shielding a queen or rook.
+func Pins(b *Board, colour int) []Pin {
+	pins, count := findPins(b, colour)
+	return append([]Pin(nil), pins[:count]...)
+}
@@ -0,0 +1,93 @@
+package board
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttackersTo(t *testing.T) {
+	b := FromFEN("4k3/8/8/3r4/8/1N3n2/3QP3/3RK3 w - - 0 1")
+	got := []string{}
+	for _, square := range AttackersTo(b, NotationToSquareIndex("d4")).Squares() {
+		got = append(got, SquareIndexToNotation(square))
+	}
+	// The rook on d1 is behind the queen, so doesn't count.
+	want := []string{"d2", "b3", "f3", "d5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Attackers of d4 should be %v, not %v", want, got)
+	}
+
+	forEachPerftPosition(t, func(b *Board) {
+		for square := 0; square < 128; square++ {
+			if !LegalSquareIndex(square) {
+				continue
+			}
+			attackers := AttackersTo(b, square)
+			for _, colour := range []int{WHITE, BLACK} {
+				pieces := attackers & Bitboard(b.colourBitboards[colour>>3])
+				if (pieces != 0) != IsAttacked(b, square, colour) {
+					t.Fatalf("%s: attackers of %s %v don't match IsAttacked", ToFEN(b), SquareIndexToNotation(square), pieces.Squares())
+				}
+			}
+		}
+	})
+}
+
+func TestAttackMap(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	attacks := AttackMap(b, WHITE)
+	counts := map[string]int{"a1": 0, "b1": 1, "d1": 1, "d2": 4, "f3": 3, "e3": 2, "a3": 2, "d4": 0}
+	for notation, count := range counts {
+		if got := attacks[NotationToSquareIndex(notation)]; got != count {
+			t.Errorf("%s should be attacked by %d white pieces, not %d", notation, count, got)
+		}
+	}
+
+	forEachPerftPosition(t, func(b *Board) {
+		for _, colour := range []int{WHITE, BLACK} {
+			attacks := AttackMap(b, colour)
+			for square := 0; square < 128; square++ {
+				if !LegalSquareIndex(square) {
+					continue
+				}
+				want := (AttackersTo(b, square) & Bitboard(b.colourBitboards[colour>>3])).Count()
+				if attacks[square] != want {
+					t.Fatalf("%s: %s should be attacked %d times, not %d", ToFEN(b), SquareIndexToNotation(square), want, attacks[square])
+				}
+			}
+		}
+	})
+}
+
+func TestPins(t *testing.T) {
+	b := FromFEN("4k3/8/8/4n3/8/8/8/4R1K1 b - - 0 1")
+	got := Pins(b, BLACK)
+	want := []Pin{{Pinned: NotationToSquareIndex("e5"), Pinner: NotationToSquareIndex("e1")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pins should be %v, not %v", want, got)
+	}
+	if got := Pins(b, WHITE); len(got) != 0 {
+		t.Errorf("White pieces should not be pinned, but got %v", got)
+	}
+
+	// The rook on a1 has two pieces in front of it, and the queen on h3
+	// isn't in line with the king.
+	b = FromFEN("4k3/8/8/b7/8/7q/3B4/rNB1K3 w - - 0 1")
+	got = Pins(b, WHITE)
+	want = []Pin{{Pinned: NotationToSquareIndex("d2"), Pinner: NotationToSquareIndex("a5")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pins should be %v, not %v", want, got)
+	}
+
+	forEachPerftPosition(t, func(b *Board) {
+		for _, colour := range []int{WHITE, BLACK} {
+			var squares uint64
+			for _, pin := range Pins(b, colour) {
+				squares |= squareBB(pin.Pinned)
+			}
+			if squares != pinned(b, colour) {
+				t.Fatalf("%s: pins %v don't match the pinned pieces", ToFEN(b), Pins(b, colour))
+			}
+		}
+	})
+}
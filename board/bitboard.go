@@ -0,0 +1,252 @@
+package board
+
+import (
+	"math/bits"
+)
+
+// Bitboards have one bit per square, with a1 as bit 0, b1 as bit 1 and so
+// on up to h8 as bit 63. Square indexes elsewhere are 0x88, so use
+// squareTo64 and squareTo88 to convert between the two.
+
+const (
+	fileA   uint64 = 0x0101010101010101
+	fileH   uint64 = fileA << 7
+	rank1   uint64 = 0xFF
+	rank2   uint64 = rank1 << 8
+	rank3   uint64 = rank1 << 16
+	rank6   uint64 = rank1 << 40
+	rank7   uint64 = rank1 << 48
+	rank8   uint64 = rank1 << 56
+	edgesBB uint64 = fileA | fileH | rank1 | rank8
+
+	darkSquares uint64 = 0xAA55AA55AA55AA55
+)
+
+func squareTo64(square int) int {
+	return (square + square&7) >> 1
+}
+
+func squareTo88(square int) int {
+	return square + square&^7
+}
+
+// popSquare removes the lowest set bit from the bitboard and returns its
+// 0x88 square index.
+func popSquare(bb *uint64) int {
+	square := bits.TrailingZeros64(*bb)
+	*bb &= *bb - 1
+	return squareTo88(square)
+}
+
+var (
+	knightAttacks [64]uint64
+	kingAttacks   [64]uint64
+	pawnAttacks   [2][64]uint64 // Indexed by colour >> 3, so black first.
+)
+
+// magic holds what is needed to look up the attacks of a slider on a square
+// for any arrangement of blocking pieces.
+type magic struct {
+	mask    uint64 // Squares where a blocker makes a difference.
+	magic   uint64
+	shift   uint
+	attacks []uint64 // The attacks for this square, indexed by the magic hash.
+}
+
+var (
+	rookMagics   [64]magic
+	bishopMagics [64]magic
+)
+
+func init() {
+	initStepAttacks()
+	initMagics(&rookMagics, LINES)
+	initMagics(&bishopMagics, DIAGONALS)
+}
+
+func initStepAttacks() {
+	for square64 := 0; square64 < 64; square64++ {
+		square := squareTo88(square64)
+		knightAttacks[square64] = stepAttacks(square, KNIGHTMOVES)
+		kingAttacks[square64] = stepAttacks(square, DIAGONALSANDLINES)
+		pawnAttacks[WHITE>>3][square64] = stepAttacks(square, NDIAGONALS)
+		pawnAttacks[BLACK>>3][square64] = stepAttacks(square, SDIAGONALS)
+	}
+}
+
+func stepAttacks(square int, offsets []int) uint64 {
+	var result uint64
+	for _, offset := range offsets {
+		if to := square + offset; LegalSquareIndex(to) {
+			result |= 1 << uint(squareTo64(to))
+		}
+	}
+	return result
+}
+
+// slidingAttacks works out the attacks of a slider the slow way, by walking
+// along each ray until it hits a blocker.
+func slidingAttacks(square int, directions []int, occupied uint64) uint64 {
+	var result uint64
+	for _, direction := range directions {
+		for to := square + direction; LegalSquareIndex(to); to += direction {
+			bit := uint64(1) << uint(squareTo64(to))
+			result |= bit
+			if occupied&bit != 0 {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// magicSeeds seed the random number generator for the squares on each rank
+// when searching for magics. These are known to find them quickly.
+var magicSeeds = [8]uint64{728, 10316, 55013, 32803, 12281, 15100, 16645, 255}
+
+// xorshift is a xorshift64* pseudo random number generator.
+type xorshift uint64
+
+func (x *xorshift) next() uint64 {
+	*x ^= *x >> 12
+	*x ^= *x << 25
+	*x ^= *x >> 27
+	return uint64(*x) * 2685821657736338717
+}
+
+// sparse returns a random number with only a few bits set, which makes a
+// better magic candidate.
+func (x *xorshift) sparse() uint64 {
+	return x.next() & x.next() & x.next()
+}
+
+// initMagics finds magic multipliers for every square by trial and error
+// and fills in the attack tables.
+func initMagics(magics *[64]magic, directions []int) {
+	occupancies := make([]uint64, 4096)
+	reference := make([]uint64, 4096)
+	epoch := make([]int, 4096)
+	attempt := 0
+
+	for square64 := 0; square64 < 64; square64++ {
+		square := squareTo88(square64)
+		m := &magics[square64]
+		r := xorshift(magicSeeds[square64>>3])
+
+		// Blockers on the edge of the board make no difference, except to
+		// rooks moving along that edge.
+		edges := (rank1|rank8)&^(rank1<<uint(square64&^7)) | (fileA|fileH)&^(fileA<<uint(square64&7))
+		m.mask = slidingAttacks(square, directions, 0) &^ edges
+		bitCount := bits.OnesCount64(m.mask)
+		m.shift = uint(64 - bitCount)
+		m.attacks = make([]uint64, 1<<uint(bitCount))
+
+		// Enumerate all subsets of the mask.
+		size := 0
+		for subset := uint64(0); ; {
+			occupancies[size] = subset
+			reference[size] = slidingAttacks(square, directions, subset)
+			size++
+			subset = (subset - m.mask) & m.mask
+			if subset == 0 {
+				break
+			}
+		}
+
+	Search:
+		for {
+			m.magic = r.sparse()
+			if bits.OnesCount64((m.mask*m.magic)>>56) < 6 {
+				continue
+			}
+			attempt++
+			for i := 0; i < size; i++ {
+				index := (occupancies[i] * m.magic) >> m.shift
+				if epoch[index] < attempt {
+					epoch[index] = attempt
+					m.attacks[index] = reference[i]
+				} else if m.attacks[index] != reference[i] {
+					continue Search
+				}
+			}
+			break
+		}
+	}
+}
+
+// rookAttacks returns the squares attacked by a rook on the given 0x88
+// square with the given pieces on the board.
+func rookAttacks(square int, occupied uint64) uint64 {
+	m := &rookMagics[squareTo64(square)]
+	return m.attacks[((occupied&m.mask)*m.magic)>>m.shift]
+}
+
+// bishopAttacks returns the squares attacked by a bishop on the given 0x88
+// square with the given pieces on the board.
+func bishopAttacks(square int, occupied uint64) uint64 {
+	m := &bishopMagics[squareTo64(square)]
+	return m.attacks[((occupied&m.mask)*m.magic)>>m.shift]
+}
+
+// pieceAttacks returns the squares attacked by a piece on the given square,
+// which for pawns is only the diagonal captures.
+func pieceAttacks(piece int, square int, occupied uint64) uint64 {
+	square64 := squareTo64(square)
+	switch GetPieceType(piece) {
+	case PAWN:
+		return pawnAttacks[GetColour(piece)>>3][square64]
+	case KNIGHT:
+		return knightAttacks[square64]
+	case BISHOP:
+		return bishopAttacks(square, occupied)
+	case ROOK:
+		return rookAttacks(square, occupied)
+	case QUEEN:
+		return bishopAttacks(square, occupied) | rookAttacks(square, occupied)
+	case KING:
+		return kingAttacks[square64]
+	}
+	return 0
+}
+
+// squareBB returns the bitboard with just the given 0x88 square set.
+func squareBB(square int) uint64 {
+	return 1 << uint(squareTo64(square))
+}
+
+// setPiece puts a piece on an empty square.
+func (b *Board) setPiece(square int, piece int) {
+	b.squares[square] = piece
+	bb := squareBB(square)
+	b.pieceBitboards[piece] |= bb
+	b.colourBitboards[GetColour(piece)>>3] |= bb
+}
+
+// removePiece removes the piece from a square, returning it.
+func (b *Board) removePiece(square int) int {
+	piece := b.squares[square]
+	if piece == EMPTY {
+		return EMPTY
+	}
+	b.squares[square] = EMPTY
+	bb := squareBB(square)
+	b.pieceBitboards[piece] &^= bb
+	b.colourBitboards[GetColour(piece)>>3] &^= bb
+	return piece
+}
+
+// movePiece moves a piece from one square to another, which must be empty.
+func (b *Board) movePiece(from int, to int) {
+	b.setPiece(to, b.removePiece(from))
+}
+
+func (b *Board) occupied() uint64 {
+	return b.colourBitboards[0] | b.colourBitboards[1]
+}
+
+// Pieces returns the squares holding the given piece, e.g. WHITE|KNIGHT.
+// The set for each piece is kept up to date as moves are made and undone,
+// so this is much quicker than searching the board for them.
+func (b *Board) Pieces(piece int) Bitboard {
+	return Bitboard(b.pieceBitboards[piece])
+}
@@ -64,30 +64,18 @@ type Board struct {
 	halfMove    int
 	moveHistory []MoveUndo
 	zobristKey  uint64
-}
 
-type Move struct {
-	from      int
-	to        int
-	promotion int
+	pieceBitboards  [15]uint64 // Indexed by piece, e.g. WHITE|KNIGHT
+	colourBitboards [2]uint64  // Indexed by colour >> 3
 }
 
 type MoveUndo struct {
-	from        int
-	to          int
-	isPromotion bool // We don't care what the promoted piece is.
-	captured    int
-	ep          int
-	halfMove    int
-	castling    int
-}
-
-func (m Move) String() string {
-	result := SquareIndexToNotation(m.from) + SquareIndexToNotation(m.to)
-	if m.promotion != 0 {
-		result += PieceToNotation(m.promotion)
-	}
-	return result
+	move       Move
+	captured   int
+	ep         int
+	halfMove   int
+	castling   int
+	zobristKey uint64
 }
 
 const InitialPositionFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
@@ -150,67 +138,15 @@ func PieceToNotation(piece int) string {
 	return notation
 }
 
+// FromFEN creates a board from a FEN string that is known to be valid, such
+// as InitialPositionFEN. It panics if the string cannot be parsed, and does
+// not check that the position is legal. Use ParseFEN for untrusted input.
 func FromFEN(fen string) *Board {
-	b := Board{}
-	fenParts := strings.Split(fen, " ")
-	boardParts := strings.Split(fenParts[0], "/")
-	for rank, line := range boardParts {
-		file := 0
-		for _, char := range line {
-			if n, err := strconv.Atoi(string(char)); err == nil {
-				file += n
-			} else {
-				piece := PieceFromNotation(char)
-				square := (7-rank)*16 + file
-				b.squares[square] = piece
-				if piece == WHITE|KING {
-					b.whiteKing = square
-				} else if piece == BLACK|KING {
-					b.blackKing = square
-				}
-				file++
-			}
-		}
-	}
-
-	b.whiteToMove = (fenParts[1] == "w")
-
-	castlingBits := map[string]int{
-		"K": 8,
-		"Q": 4,
-		"k": 2,
-		"q": 1,
+	b, err := decodeFEN(fen)
+	if err != nil {
+		panic(err)
 	}
-
-	for letter, bit := range castlingBits {
-		if strings.Contains(fenParts[2], letter) {
-			b.castling |= bit
-		}
-	}
-
-	if fenParts[3] == "-" {
-		b.ep = -1
-	} else {
-		b.ep = NotationToSquareIndex(fenParts[3])
-	}
-
-	if len(fenParts) > 4 {
-		if halfMove, err := strconv.Atoi(fenParts[4]); err == nil {
-			b.halfMove = halfMove
-		}
-	} else {
-		b.halfMove = 0
-	}
-
-	if len(fenParts) > 5 {
-		if fullMove, err := strconv.Atoi(fenParts[5]); err == nil {
-			b.fullMove = fullMove
-		}
-	} else {
-		b.fullMove = 1
-	}
-
-	return &b
+	return b
 }
 
 func ToFEN(b *Board) string {
@@ -244,6 +180,9 @@ func ToFEN(b *Board) string {
 		result.WriteString("b")
 	}
 	result.WriteString(" ")
+	if b.castling == 0 {
+		result.WriteString("-")
+	}
 	for i, symbol := range []rune{'K', 'Q', 'k', 'q'} {
 		if b.castling&(1<<uint(3-i)) > 0 {
 			result.WriteRune(symbol)
@@ -263,39 +202,106 @@ func ToFEN(b *Board) string {
 
 // GenerateMoves generates pseudo-legal moves for the position given
 func GenerateMoves(b *Board) []Move {
-	result := make([]Move, 0)
-	for i := 0; i < 128; i++ {
-		if b.squares[i] == EMPTY {
+	var list MoveList
+	GenerateMovesInto(b, &list)
+	return append([]Move(nil), list.Moves()...)
+}
+
+// GenerateMovesInto adds the pseudo-legal moves for the position to the end
+// of the list.
+func GenerateMovesInto(b *Board, list *MoveList) {
+	pieces := b.colourBitboards[ColourToMove(b)>>3]
+	for pieces != 0 {
+		generatePieceMoves(b, popSquare(&pieces), list)
+	}
+}
+
+func GeneratePieceMoves(b *Board, i int) []Move {
+	var list MoveList
+	generatePieceMoves(b, i, &list)
+	return append([]Move(nil), list.Moves()...)
+}
+
+// GenerateSingleMoves returns the pseudo-legal moves from the given square
+// to each square one offset away, for a knight or king.
+func GenerateSingleMoves(b *Board, i int, offsets []int) []Move {
+	var targets uint64
+	for _, offset := range offsets {
+		if toSquare := i + offset; LegalSquareIndex(toSquare) {
+			targets |= squareBB(toSquare)
+		}
+	}
+	return movesToSquares(b, i, targets)
+}
+
+// GenerateSlides returns the pseudo-legal moves from the given square along
+// each of the offsets, for a bishop, rook or queen.
+func GenerateSlides(b *Board, i int, offsets []int) []Move {
+	return movesToSquares(b, i, slidingAttacks(i, offsets, b.occupied()))
+}
+
+// movesToSquares returns the moves from the given square to each of the
+// squares in the bitboard which isn't occupied by the piece's own side.
+func movesToSquares(b *Board, i int, targets uint64) []Move {
+	var list MoveList
+	ownPieces := b.colourBitboards[GetColour(b.squares[i])>>3]
+	addMovesToSquares(b, i, targets&^ownPieces, &list)
+	return append([]Move(nil), list.Moves()...)
+}
+
+// GeneratePawnMoves returns the pseudo-legal moves for the pawn on the
+// given square, moving in the forward direction and capturing along the
+// capture offsets. Moves to the last rank are returned once for each
+// promotion piece, queens first.
+func GeneratePawnMoves(b *Board, i int, forward int, captureOffsets []int, onHomeRank bool) []Move {
+	var targets uint64
+	if toSquare := i + forward; LegalSquareIndex(toSquare) && b.squares[toSquare] == EMPTY {
+		targets |= squareBB(toSquare)
+		if onHomeRank && b.squares[toSquare+forward] == EMPTY {
+			targets |= squareBB(toSquare + forward)
+		}
+	}
+	ownColour := GetColour(b.squares[i])
+	for _, offset := range captureOffsets {
+		toSquare := i + offset
+		if !LegalSquareIndex(toSquare) {
 			continue
 		}
+		if toSquare == b.ep || b.squares[toSquare] != EMPTY && GetColour(b.squares[toSquare]) != ownColour {
+			targets |= squareBB(toSquare)
+		}
+	}
+	var list MoveList
+	addPawnMoves(b, i, targets, &list)
+	return append([]Move(nil), list.Moves()...)
+}
 
-		if (b.whiteToMove && GetColour(b.squares[i]) == WHITE) || (!b.whiteToMove && GetColour(b.squares[i]) == BLACK) {
-			moves := GeneratePieceMoves(b, i)
-			result = append(result, moves...)
+// GeneratePawnSlides returns the valid non-capturing moves for
+// a pawn on the home rank. Behaviour is undefined for pawns on
+// any other rank.
+func GeneratePawnSlides(b *Board, i int, offset int) []Move {
+	var result []Move
+	toSquare := i + offset
+	if b.squares[toSquare] == EMPTY {
+		result = append(result, NewMove(b, i, toSquare, EMPTY))
+		toSquare += offset
+		if b.squares[toSquare] == EMPTY {
+			result = append(result, NewMove(b, i, toSquare, EMPTY))
 		}
 	}
 	return result
 }
 
-func GeneratePieceMoves(b *Board, i int) []Move {
+func generatePieceMoves(b *Board, i int, list *MoveList) {
 	piece := b.squares[i]
+	ownPieces := b.colourBitboards[GetColour(piece)>>3]
 	switch GetPieceType(piece) {
 	case PAWN:
-		if b.whiteToMove {
-			return GeneratePawnMoves(b, i, N, NDIAGONALS, i&0xF0 == 0x10)
-		} else {
-			return GeneratePawnMoves(b, i, S, SDIAGONALS, i&0xF0 == 0x60)
-		}
-	case KNIGHT:
-		return GenerateSingleMoves(b, i, KNIGHTMOVES)
-	case BISHOP:
-		return GenerateSlides(b, i, DIAGONALS)
-	case ROOK:
-		return GenerateSlides(b, i, LINES)
-	case QUEEN:
-		return GenerateSlides(b, i, DIAGONALSANDLINES)
+		generatePawnMoves(b, i, list)
+	case KNIGHT, BISHOP, ROOK, QUEEN:
+		addMovesToSquares(b, i, pieceAttacks(piece, i, b.occupied())&^ownPieces, list)
 	case KING:
-		kingMoves := GenerateSingleMoves(b, i, DIAGONALSANDLINES)
+		addMovesToSquares(b, i, kingAttacks[squareTo64(i)]&^ownPieces, list)
 		var castleKingsideAllowed, castleQueensideAllowed bool
 		if b.whiteToMove {
 			castleKingsideAllowed = b.castling&8 == 8
@@ -305,115 +311,74 @@ func GeneratePieceMoves(b *Board, i int) []Move {
 			castleQueensideAllowed = b.castling&1 == 1
 		}
 		if castleKingsideAllowed && CastlingLegal(b, i, E) {
-			kingMoves = append(kingMoves, Move{i, i + 2, EMPTY})
+			list.Add(newMove(i, i+2, kingCastleFlag))
 		}
 		if castleQueensideAllowed && CastlingLegal(b, i, W) {
-			kingMoves = append(kingMoves, Move{i, i - 2, EMPTY})
+			list.Add(newMove(i, i-2, queenCastleFlag))
 		}
-		return kingMoves
 	}
-
-	// TODO: Raise error
-	return make([]Move, 0)
 }
 
-func GenerateSingleMoves(b *Board, i int, offsets []int) []Move {
-	result := make([]Move, 0)
-	ownColour := GetColour(b.squares[i])
-	for _, offset := range offsets {
-		if toSquare := i + offset; LegalSquareIndex(toSquare) {
-			if b.squares[toSquare] == 0 || GetColour(b.squares[toSquare]) != ownColour {
-				result = append(result, Move{i, toSquare, EMPTY})
-			}
-		}
-	}
-	return result
-}
-
-func GenerateSlides(b *Board, i int, offsets []int) []Move {
-	result := make([]Move, 0)
-	ownColour := GetColour(b.squares[i])
-	for _, offset := range offsets {
-		for toSquare := i + offset; toSquare >= 0 && LegalSquareIndex(toSquare); toSquare += offset {
-			if b.squares[toSquare] == 0 {
-				result = append(result, Move{i, toSquare, EMPTY})
-			} else if GetColour(b.squares[toSquare]) != ownColour {
-				result = append(result, Move{i, toSquare, EMPTY})
-				break
-			} else {
-				break
-			}
+// addMovesToSquares adds a move from the given square to each of the
+// squares in the bitboard, for any piece except a pawn.
+func addMovesToSquares(b *Board, from int, targets uint64, list *MoveList) {
+	for targets != 0 {
+		to := popSquare(&targets)
+		if b.squares[to] == EMPTY {
+			list.Add(newMove(from, to, quietFlag))
+		} else {
+			list.Add(newMove(from, to, captureFlag))
 		}
 	}
-	return result
 }
 
-func GeneratePawnMoves(b *Board, i int, forward int, captureOffsets []int, onHomeRank bool) []Move {
+// pawnTargets returns the squares the pawn on the given square can move to.
+func pawnTargets(b *Board, i int) uint64 {
 	ownColour := GetColour(b.squares[i])
-	var result []Move
-	if onHomeRank {
-		result = GeneratePawnSlides(b, i, forward)
-	} else {
-		result = make([]Move, 0)
-		if toSquare := i + forward; toSquare&0x88 == 0 && b.squares[toSquare] == 0 {
-			result = append(result, Move{i, toSquare, EMPTY})
-		}
+	forward, homeRank := N, 0x10
+	if ownColour == BLACK {
+		forward, homeRank = S, 0x60
 	}
 
-	for _, offset := range captureOffsets {
-		toSquare := i + offset
-		if (toSquare)&0x88 > 0 {
-			continue
-		}
-		if b.ep == toSquare {
-			result = append(result, Move{i, toSquare, EMPTY})
-			continue
+	var result uint64
+	if toSquare := i + forward; LegalSquareIndex(toSquare) && b.squares[toSquare] == EMPTY {
+		result |= squareBB(toSquare)
+		if i&0xF0 == homeRank && b.squares[toSquare+forward] == EMPTY {
+			result |= squareBB(toSquare + forward)
 		}
-		if b.squares[toSquare] == 0 {
-			continue
-		}
-		if GetColour(b.squares[toSquare]) == ownColour {
-			continue
-		}
-		result = append(result, Move{i, toSquare, EMPTY})
 	}
 
-	// Convert moves to promotion moves where required.
-	// Loads start of array with queens, then rooks etc.
-	if ownColour == WHITE && i&0xF0 == 0x60 || ownColour == BLACK && i&0xF0 == 0x10 {
-		var promotions []Move
-		promotions = make([]Move, len(result)*4)
-		for pieceIndex, piece := range PROMOTIONPIECES {
-			for moveIndex, move := range result {
-				promotions[pieceIndex*len(result)+moveIndex] = Move{move.from, move.to, ownColour | piece}
-			}
-		}
-		return promotions
+	captures := b.colourBitboards[GetOpponentColour(ownColour)>>3]
+	if b.ep > 0 {
+		captures |= squareBB(b.ep)
 	}
+	return result | captures&pawnAttacks[ownColour>>3][squareTo64(i)]
+}
 
-	return result
+// generatePawnMoves adds the moves of a pawn to the list. Moves to the last
+// rank are added once for each promotion piece, queens first.
+func generatePawnMoves(b *Board, i int, list *MoveList) {
+	addPawnMoves(b, i, pawnTargets(b, i), list)
 }
 
-// GeneratePawnSlides returns the valid non-capturing moves for
-// a pawn on the home rank. Behaviour is undefined for pawns on
-// any other rank.
-func GeneratePawnSlides(b *Board, i int, offset int) []Move {
-	result := make([]Move, 0)
-	toSquare := i + offset
-	if b.squares[toSquare] == 0 {
-		result = append(result, Move{i, toSquare, EMPTY})
-		toSquare += offset
-		if b.squares[toSquare] == 0 {
-			result = append(result, Move{i, toSquare, EMPTY})
+func addPawnMoves(b *Board, i int, targets uint64, list *MoveList) {
+	if targets&(rank1|rank8) == 0 {
+		for targets != 0 {
+			list.Add(NewMove(b, i, popSquare(&targets), EMPTY))
+		}
+		return
+	}
+	for _, piece := range PROMOTIONPIECES {
+		for remaining := targets; remaining != 0; {
+			list.Add(NewMove(b, i, popSquare(&remaining), piece))
 		}
 	}
-
-	return result
 }
 
 // LegalMove decides whether a pseudo-legal move is actually legal
 // i.e. does it result in the moving side being in check?
-// TODO: This is a very slow implementation - do static calculation
+// This tries the move on the board, so use GenerateLegalMoves rather than
+// calling it for every move.
 func LegalMove(b *Board, move Move) bool {
 	colourMoving := ColourToMove(b)
 	MakeMove(b, move)
@@ -422,6 +387,28 @@ func LegalMove(b *Board, move Move) bool {
 	return result
 }
 
+// Clone returns a copy of the board which can be changed, or used from
+// another goroutine, without affecting the original. The move history is
+// copied too, so moves made before cloning can still be undone on the copy
+// and repetitions are still found.
+func (b *Board) Clone() *Board {
+	c := *b
+	c.moveHistory = append([]MoveUndo(nil), b.moveHistory...)
+	return &c
+}
+
+// FullMove returns the number of the current move, which starts at 1 and
+// goes up after each move by black.
+func (b *Board) FullMove() int {
+	return b.fullMove
+}
+
+// HalfMove returns the number of half moves since the last capture or pawn
+// move, for the fifty move rule.
+func (b *Board) HalfMove() int {
+	return b.halfMove
+}
+
 func ColourToMove(b *Board) int {
 	if b.whiteToMove {
 		return WHITE
@@ -430,157 +417,243 @@ func ColourToMove(b *Board) int {
 }
 
 func MakeMove(b *Board, move Move) {
+	from, to := move.From(), move.To()
 	undo := MoveUndo{
-		from:        move.from,
-		to:          move.to,
-		isPromotion: move.promotion != EMPTY,
-		captured:    b.squares[move.to],
-		ep:          b.ep,
-		halfMove:    b.halfMove,
-		castling:    b.castling,
-	}
-
-	resetEp := true
-
-	movedPiece := GetPieceType(b.squares[move.from])
-
-	if movedPiece == PAWN {
-		if move.to == b.ep && (move.to&0x0F != move.from&0x0F) {
-			capturedSquare := move.from&0xF0 | move.to&0x0F
-			undo.captured = b.squares[capturedSquare]
-			b.squares[capturedSquare] = EMPTY
-		} else if offset := move.to - move.from; offset == 32 || offset == -32 {
-			b.ep = move.from + offset/2
-			resetEp = false
-		}
-	} else if movedPiece == KING {
-		if GetColour(b.squares[move.from]) == WHITE {
-			b.whiteKing = move.to
+		move:       move,
+		captured:   b.squares[to],
+		ep:         b.ep,
+		halfMove:   b.halfMove,
+		castling:   b.castling,
+		zobristKey: b.zobristKey,
+	}
+
+	key := b.zobristKey
+	key ^= ZobristKeys.PiecePosition[b.squares[from]][from]
+	key ^= ZobristKeys.PiecePosition[b.squares[to]][to]
+	key ^= epZobristKey(b)
+
+	b.ep = -1
+
+	movedPiece := GetPieceType(b.squares[from])
+
+	switch {
+	case move.IsEnPassant():
+		capturedSquare := from&0xF0 | to&0x0F
+		undo.captured = b.removePiece(capturedSquare)
+		key ^= ZobristKeys.PiecePosition[undo.captured][capturedSquare]
+	case move.IsDoublePush():
+		b.ep = (from + to) / 2
+	case move.IsCastling():
+		// Rook
+		rookFrom, rookTo := to+1, from+1
+		if move.flags() == queenCastleFlag {
+			rookFrom, rookTo = to-2, from-1
+		}
+		rook := b.squares[rookFrom]
+		key ^= ZobristKeys.PiecePosition[rook][rookFrom] ^ ZobristKeys.PiecePosition[rook][rookTo]
+		b.movePiece(rookFrom, rookTo)
+	}
+
+	if movedPiece == KING {
+		if GetColour(b.squares[from]) == WHITE {
+			b.whiteKing = to
 			b.castling &= 0x03
 		} else {
-			b.blackKing = move.to
+			b.blackKing = to
 			b.castling &= 0x0C
 		}
-		offset := move.to - move.from
-
-		if offset == 2 { // Kingside castling
-			// Rook
-			b.squares[move.from+1] = b.squares[move.to+1]
-			b.squares[move.to+1] = EMPTY
-		} else if offset == -2 { // Queenside castling
-			// Rook
-			b.squares[move.from-1] = b.squares[move.to-2]
-			b.squares[move.to-2] = EMPTY
-		}
 	} else if movedPiece == ROOK {
 		if b.whiteToMove {
-			if move.from == 0x00 && b.castling&0x04 == 0x04 {
+			if from == 0x00 && b.castling&0x04 == 0x04 {
 				b.castling &= ^0x04
 			}
-			if move.from == 0x07 && b.castling&0x08 == 0x08 {
+			if from == 0x07 && b.castling&0x08 == 0x08 {
 				b.castling &= ^0x08
 			}
 		} else {
-			if move.from == 0x70 && b.castling&0x01 == 0x01 {
+			if from == 0x70 && b.castling&0x01 == 0x01 {
 				b.castling &= ^0x01
 			}
-			if move.from == 0x77 && b.castling&0x02 == 0x02 {
+			if from == 0x77 && b.castling&0x02 == 0x02 {
 				b.castling &= ^0x02
 			}
 		}
 	}
 
 	// Update castling if rook captured
-	if b.squares[move.to]&ROOK == ROOK {
-		if GetColour(b.squares[move.from]) == WHITE {
-			if move.to == 0x70 {
+	if b.squares[to]&ROOK == ROOK {
+		if GetColour(b.squares[from]) == WHITE {
+			if to == 0x70 {
 				b.castling &= ^0x01
-			} else if move.to == 0x77 {
+			} else if to == 0x77 {
 				b.castling &= ^0x02
 			}
 		} else {
-			if move.to == 0x00 {
+			if to == 0x00 {
 				b.castling &= ^0x04
-			} else if move.to == 0x07 {
+			} else if to == 0x07 {
 				b.castling &= ^0x08
 			}
 		}
 	}
 
-	if resetEp {
-		b.ep = -1
-	}
-
 	b.moveHistory = append(b.moveHistory, undo)
 
 	// Move the actual piece
-	if move.promotion == EMPTY { // Checking undo.promotion would be marginally quicker.
-		b.squares[move.to] = b.squares[move.from]
+	b.removePiece(to)
+	piece := b.removePiece(from)
+	if move.IsPromotion() {
+		piece = GetColour(piece) | move.Promotion()
+	}
+	b.setPiece(to, piece)
+	key ^= ZobristKeys.PiecePosition[b.squares[to]][to]
+
+	key ^= castlingZobristKey(b.castling ^ undo.castling)
+	key ^= ZobristKeys.WhiteToMove
+
+	// Pawn moves and captures can't be reversed, so reset the fifty move clock.
+	if movedPiece == PAWN || undo.captured != EMPTY {
+		b.halfMove = 0
 	} else {
-		b.squares[move.to] = move.promotion
+		b.halfMove++
+	}
+	if !b.whiteToMove {
+		b.fullMove++
 	}
 
-	b.squares[move.from] = EMPTY
 	b.whiteToMove = !b.whiteToMove
+
+	b.zobristKey = key ^ epZobristKey(b)
+
+	if validateMoves {
+		mustValidate(b, "MakeMove "+move.String())
+	}
 }
 
 func UndoMove(b *Board) {
 	a := b.moveHistory
 	var lastMove MoveUndo
 	lastMove, b.moveHistory = a[len(a)-1], a[:len(a)-1]
+	move := lastMove.move
+	from, to := move.From(), move.To()
 
-	b.squares[lastMove.from] = b.squares[lastMove.to]
-	b.squares[lastMove.to] = lastMove.captured
+	piece := b.removePiece(to)
+	if move.IsPromotion() {
+		piece = PAWN | GetColour(piece)
+	}
+	b.setPiece(from, piece)
 
 	b.whiteToMove = !b.whiteToMove
+	if !b.whiteToMove {
+		b.fullMove--
+	}
 	b.ep = lastMove.ep
 	b.halfMove = lastMove.halfMove
 	b.castling = lastMove.castling
+	b.zobristKey = lastMove.zobristKey
+
+	switch {
+	case move.IsEnPassant():
+		capturedSquare := from&0xF0 | to&0x0F
+		b.setPiece(capturedSquare, lastMove.captured)
+	case lastMove.captured != EMPTY:
+		b.setPiece(to, lastMove.captured)
+	case move.flags() == kingCastleFlag:
+		// Rook
+		b.movePiece(from+1, to+1)
+	case move.flags() == queenCastleFlag:
+		// Rook
+		b.movePiece(from-1, to-2)
+	}
+
+	if GetPieceType(piece) == KING {
+		if GetColour(piece) == WHITE {
+			b.whiteKing = from
+		} else {
+			b.blackKing = from
+		}
+	}
 
-	// Promotion
-	if lastMove.isPromotion {
-		b.squares[lastMove.from] = PAWN | GetColour(b.squares[lastMove.from])
+	if validateMoves {
+		mustValidate(b, "UndoMove "+move.String())
 	}
+}
 
-	movedPiece := GetPieceType(b.squares[lastMove.from])
+// MakeNullMove passes the turn to the other side without moving, e.g. for
+// null move pruning. It must be undone with UndoNullMove rather than
+// UndoMove. The side to move must not be in check.
+func MakeNullMove(b *Board) {
+	b.moveHistory = append(b.moveHistory, MoveUndo{
+		move:       NullMove,
+		ep:         b.ep,
+		halfMove:   b.halfMove,
+		castling:   b.castling,
+		zobristKey: b.zobristKey,
+	})
+
+	b.zobristKey ^= epZobristKey(b) ^ ZobristKeys.WhiteToMove
+	b.ep = -1
+	b.halfMove++
+	if !b.whiteToMove {
+		b.fullMove++
+	}
+	b.whiteToMove = !b.whiteToMove
 
-	if movedPiece == PAWN && lastMove.to == lastMove.ep && (lastMove.to&0x0F != lastMove.from&0x0F) {
-		capturedSquare := lastMove.from&0xF0 | lastMove.to&0x0F
-		b.squares[capturedSquare] = lastMove.captured
-		b.squares[lastMove.to] = EMPTY
-	} else if movedPiece == KING {
-		if GetColour(b.squares[lastMove.from]) == WHITE {
-			b.whiteKing = lastMove.from
-		} else {
-			b.blackKing = lastMove.from
-		}
-		offset := lastMove.to - lastMove.from
-
-		if offset == 2 { // Kingside castling
-			// Rook
-			b.squares[lastMove.to+1] = b.squares[lastMove.from+1]
-			b.squares[lastMove.from+1] = EMPTY
-		} else if offset == -2 { // Queenside castling
-			// Rook
-			b.squares[lastMove.to-2] = b.squares[lastMove.from-1]
-			b.squares[lastMove.from-1] = EMPTY
+	if validateMoves {
+		mustValidate(b, "MakeNullMove")
+	}
+}
+
+// UndoNullMove takes back a move made with MakeNullMove.
+func UndoNullMove(b *Board) {
+	a := b.moveHistory
+	var lastMove MoveUndo
+	lastMove, b.moveHistory = a[len(a)-1], a[:len(a)-1]
+
+	b.whiteToMove = !b.whiteToMove
+	if !b.whiteToMove {
+		b.fullMove--
+	}
+	b.ep = lastMove.ep
+	b.halfMove = lastMove.halfMove
+	b.zobristKey = lastMove.zobristKey
+
+	if validateMoves {
+		mustValidate(b, "UndoNullMove")
+	}
+}
+
+// IsRepetition reports whether the current position has occurred at least
+// n times, including this one. Only positions since the last capture or pawn
+// move are checked, as none before that can be the same.
+func (b *Board) IsRepetition(n int) bool {
+	count := 1
+	limit := len(b.moveHistory) - b.halfMove
+	if limit < 0 {
+		limit = 0
+	}
+	// Positions with the other side to move can't match, so go back two at a time.
+	for i := len(b.moveHistory) - 2; i >= limit; i -= 2 {
+		if b.moveHistory[i].zobristKey == b.zobristKey {
+			count++
+			if count >= n {
+				return true
+			}
 		}
 	}
+	return count >= n
 }
 
 // MakeMoveFromNotation makes the given move. This currently only supports UCI
-// move format, so castling is, for example, e1g1.
-func MakeMoveFromNotation(b *Board, move string) {
-	// TODO: Support promotions and castling
-	m := Move{NotationToSquareIndex(move[:2]), NotationToSquareIndex(move[2:4]), EMPTY}
-	if len(move) > 4 {
-		promotionPiece := PieceFromNotation(rune(move[4]))
-		if b.whiteToMove {
-			promotionPiece |= WHITE
-		}
-		m.promotion = promotionPiece
+// move format, so castling is, for example, e1g1. If the move isn't legal
+// the board is left as it was and a *UCIMoveError is returned.
+func MakeMoveFromNotation(b *Board, move string) error {
+	m, err := ParseUCI(b, move)
+	if err != nil {
+		return err
 	}
 	MakeMove(b, m)
+	return nil
 }
 
 func LegalSquareIndex(i int) bool {
@@ -631,81 +704,27 @@ func CastlingLegal(b *Board, i int, direction int) bool {
 // the given colour.
 // Note that for e.p. only the ep square returns true, not the attacked pawn's square
 func IsAttacked(b *Board, square int, colour int) bool {
+	square64 := squareTo64(square)
 
-	var pawnAttacks []int
-	if colour == WHITE {
-		pawnAttacks = NDIAGONALS
-	} else { // Assume calling code is correct to avoid extra if
-		pawnAttacks = SDIAGONALS
+	// A pawn attacks the square if a pawn of the other colour on the square
+	// would attack it.
+	if pawnAttacks[GetOpponentColour(colour)>>3][square64]&b.pieceBitboards[colour|PAWN] != 0 {
+		return true
 	}
-
-	// Knights
-	for _, knightMove := range KNIGHTMOVES {
-		testSquare := square - knightMove
-		if LegalSquareIndex(testSquare) && b.squares[testSquare] == colour|KNIGHT {
-			return true
-		}
+	if knightAttacks[square64]&b.pieceBitboards[colour|KNIGHT] != 0 {
+		return true
 	}
-
-	// Rays
-	for _, dir := range DIAGONALS {
-		for i := 1; i < 8; i++ {
-			testSquare := square - (i * dir)
-			if !LegalSquareIndex(testSquare) {
-				break
-			}
-			if b.squares[testSquare] != EMPTY {
-				if b.squares[testSquare] == colour|BISHOP {
-					return true
-				}
-				if b.squares[testSquare] == colour|QUEEN {
-					return true
-				}
-				break
-			}
-		}
-	}
-	for _, dir := range LINES {
-		for i := 1; i < 8; i++ {
-			testSquare := square - (i * dir)
-			if !LegalSquareIndex(testSquare) {
-				break
-			}
-			if b.squares[testSquare] != EMPTY {
-				if b.squares[testSquare] == colour|ROOK {
-					return true
-				}
-				if b.squares[testSquare] == colour|QUEEN {
-					return true
-				}
-				break
-			}
-		}
-	}
-
-	// Pawns
-	for _, pawnMove := range pawnAttacks {
-		testSquare := square - pawnMove
-		if LegalSquareIndex(testSquare) && b.squares[testSquare] == colour|PAWN {
-			return true
-		}
+	if kingAttacks[square64]&b.pieceBitboards[colour|KING] != 0 {
+		return true
 	}
 
-	// King
-	var offset int
-	if colour == WHITE {
-		offset = square - b.whiteKing
-	} else {
-		offset = square - b.blackKing
-	}
-
-	for _, dir := range DIAGONALSANDLINES {
-		if offset == dir {
-			return true
-		}
+	// Rays
+	occupied := b.occupied()
+	queens := b.pieceBitboards[colour|QUEEN]
+	if bishopAttacks(square, occupied)&(b.pieceBitboards[colour|BISHOP]|queens) != 0 {
+		return true
 	}
-
-	return false
+	return rookAttacks(square, occupied)&(b.pieceBitboards[colour|ROOK]|queens) != 0
 }
 
 // IsCheck tests whether the given colour is in check.
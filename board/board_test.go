@@ -1,7 +1,10 @@
 package board
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -43,6 +46,7 @@ func TestToFEN(t *testing.T) {
 		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
 		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", // kiwipete
 		"r3k2r/p1ppqpb1/bn2Pnp1/4N3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1",  // kiwipete + d5d6
+		"4k3/8/8/8/8/8/8/4K3 w - - 0 1",
 	}
 	for i, fen := range fens {
 		b := FromFEN(fen)
@@ -130,9 +134,52 @@ func TestGenerateMoves(t *testing.T) {
 
 }
 
+// The older generators for each kind of piece give the same moves as
+// GeneratePieceMoves, apart from castling.
+func TestPieceGenerators(t *testing.T) {
+	forEachPerftPosition(t, func(b *Board) {
+		for pieces := b.colourBitboards[ColourToMove(b)>>3]; pieces != 0; {
+			i := popSquare(&pieces)
+			var got []Move
+			switch GetPieceType(b.squares[i]) {
+			case PAWN:
+				if b.whiteToMove {
+					got = GeneratePawnMoves(b, i, N, NDIAGONALS, i&0xF0 == 0x10)
+				} else {
+					got = GeneratePawnMoves(b, i, S, SDIAGONALS, i&0xF0 == 0x60)
+				}
+			case KNIGHT:
+				got = GenerateSingleMoves(b, i, KNIGHTMOVES)
+			case BISHOP:
+				got = GenerateSlides(b, i, DIAGONALS)
+			case ROOK:
+				got = GenerateSlides(b, i, LINES)
+			case QUEEN:
+				got = GenerateSlides(b, i, DIAGONALSANDLINES)
+			case KING:
+				got = GenerateSingleMoves(b, i, DIAGONALSANDLINES)
+			}
+			var want []Move
+			for _, m := range GeneratePieceMoves(b, i) {
+				if !m.IsCastling() {
+					want = append(want, m)
+				}
+			}
+			if !reflect.DeepEqual(moveStrings(got), moveStrings(want)) {
+				t.Fatalf("%s: moves from %s should be %v, not %v", ToFEN(b), SquareIndexToNotation(i), moveStrings(want), moveStrings(got))
+			}
+		}
+	})
+
+	b := FromFEN(InitialPositionFEN)
+	if got := moveStrings(GeneratePawnSlides(b, NotationToSquareIndex("e2"), N)); !reflect.DeepEqual(got, []string{"e2e3", "e2e4"}) {
+		t.Errorf("Pawn slides should be e2e3 and e2e4, not %v", got)
+	}
+}
+
 func TestMakeMove(t *testing.T) {
 	b := FromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
-	move := Move{0x14, 0x34, EMPTY}
+	move := NewMove(b, 0x14, 0x34, EMPTY)
 
 	MakeMove(b, move)
 
@@ -149,7 +196,7 @@ func TestMakeMove(t *testing.T) {
 
 	// Castling king side
 	b = FromFEN("r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQK2R w KQkq -")
-	move = Move{0x04, 0x06, EMPTY}
+	move = NewMove(b, 0x04, 0x06, EMPTY)
 
 	MakeMove(b, move)
 
@@ -165,7 +212,7 @@ func TestMakeMove(t *testing.T) {
 
 	b = FromFEN("r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQK2R b KQkq -")
 
-	move = Move{0x74, 0x76, EMPTY}
+	move = NewMove(b, 0x74, 0x76, EMPTY)
 	MakeMove(b, move)
 
 	if b.squares[0x75] != BLACK|ROOK {
@@ -181,7 +228,7 @@ func TestMakeMove(t *testing.T) {
 	// Castling queens side
 	b = FromFEN("r3kbnr/pppb1ppp/2np4/4p3/4P2q/2NPBQ2/PPP2PPP/R3KBNR w KQkq -")
 
-	move = Move{0x04, 0x02, EMPTY}
+	move = NewMove(b, 0x04, 0x02, EMPTY)
 
 	MakeMove(b, move)
 
@@ -197,7 +244,7 @@ func TestMakeMove(t *testing.T) {
 
 	// e.p.
 	b = FromFEN("r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6 0 1")
-	move = Move{0x44, 0x53, EMPTY}
+	move = NewMove(b, 0x44, 0x53, EMPTY)
 	MakeMove(b, move)
 	if b.squares[0x53] != WHITE|PAWN {
 		t.Errorf("d6 should be a white pawn")
@@ -209,32 +256,34 @@ func TestMakeMove(t *testing.T) {
 		t.Errorf("captured should be black pawn")
 	}
 
-	b = FromFEN("r2k3r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/1R2K2R w K -")
-	MakeMove(b, Move{0x62, 0x42, EMPTY})
+	b = FromFEN("r2k3r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/1R2K2R b K -")
+	MakeMove(b, NewMove(b, 0x62, 0x42, EMPTY))
 	if b.ep != 0x52 {
 		t.Errorf("e.p. square should be c6 after c5c7, not %X", b.ep)
 	}
 
 	// castling
 	// white
-	correct := map[Move]int{
-		{0x00, 0x10, EMPTY}: 0x0B,
-		{0x07, 0x37, EMPTY}: 0x07,
+	correct := map[[2]int]int{
+		{0x00, 0x10}: 0x0B,
+		{0x07, 0x37}: 0x07,
 	}
-	for move, castling := range correct {
+	for squares, castling := range correct {
 		b = FromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq -")
+		move := NewMove(b, squares[0], squares[1], EMPTY)
 		MakeMove(b, move)
 		if b.castling != castling {
 			t.Errorf("Castling after %s should be %b, not %b", move, castling, b.castling)
 		}
 	}
 	// black
-	correct = map[Move]int{
-		{0x70, 0x60, EMPTY}: 0x0E,
-		{0x77, 0x37, EMPTY}: 0x0D,
+	correct = map[[2]int]int{
+		{0x70, 0x60}: 0x0E,
+		{0x77, 0x37}: 0x0D,
 	}
-	for move, castling := range correct {
+	for squares, castling := range correct {
 		b = FromFEN("r3k2r/8/8/8/8/8/8/R3K2R b KQkq -")
+		move := NewMove(b, squares[0], squares[1], EMPTY)
 		MakeMove(b, move)
 		if b.castling != castling {
 			t.Errorf("Castling after %s should be %b, not %b", move, castling, b.castling)
@@ -245,19 +294,19 @@ func TestMakeMove(t *testing.T) {
 	if b.castling != 11 {
 		t.Errorf("Kkq should be 11")
 	}
-	MakeMove(b, Move{0x74, 0x73, EMPTY})
+	MakeMove(b, NewMove(b, 0x74, 0x73, EMPTY))
 	if b.castling != 8 {
 		t.Errorf("Castling should be 8")
 	}
 
 	// Castling after rook capture
 	b = FromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq -")
-	MakeMove(b, Move{0x00, 0x70, EMPTY})
+	MakeMove(b, NewMove(b, 0x00, 0x70, EMPTY))
 	if b.castling != 10 {
 		t.Errorf("Castling should be %b not %b", 10, b.castling)
 	}
 	b = FromFEN("8/8/8/8/8/8/1k6/R3K3 b Q -")
-	MakeMove(b, Move{0x11, 0x00, EMPTY})
+	MakeMove(b, NewMove(b, 0x11, 0x00, EMPTY))
 	if b.castling != 0 {
 		t.Errorf("Castling should be %b not %b", 0, b.castling)
 	}
@@ -285,24 +334,69 @@ func TestMakeMoveFromNotation(t *testing.T) {
 	if b.squares[0x70] != WHITE|QUEEN {
 		t.Errorf("a8 should be white queen")
 	}
+
+	// Illegal moves leave the board alone.
+	b = FromFEN(InitialPositionFEN)
+	for _, move := range []string{"e3e4", "e7e5", "e1g1", "e2e4q", "e2", "i2i4"} {
+		if err := MakeMoveFromNotation(b, move); err == nil {
+			t.Errorf("%s should be rejected", move)
+		}
+		if fen := ToFEN(b); fen != InitialPositionFEN {
+			t.Errorf("%s should not change the board, but gave %s", move, fen)
+		}
+	}
+}
+
+func TestParseUCI(t *testing.T) {
+	tests := []struct {
+		fen  string
+		uci  string
+		want error
+	}{
+		{InitialPositionFEN, "e2e4", nil},
+		{InitialPositionFEN, "g1f3", nil},
+		{InitialPositionFEN, "e2e5", ErrUCIIllegal},
+		{InitialPositionFEN, "e4e5", ErrUCIIllegal},
+		{InitialPositionFEN, "e7e5", ErrUCIIllegal},
+		{InitialPositionFEN, "0000", ErrUCISyntax},
+		{InitialPositionFEN, "e2e4x", ErrUCISyntax},
+		{InitialPositionFEN, "e2-e4", ErrUCISyntax},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", nil},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8K", ErrUCISyntax},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8", ErrUCIIllegal},
+		// The king is in check from the rook.
+		{"4k3/8/8/8/8/8/8/r3K2R w K - 0 1", "e1g1", ErrUCIIllegal},
+		{"4k3/8/8/8/8/8/8/r3K2R w K - 0 1", "h1h8", ErrUCIIllegal},
+		{"4k3/8/8/8/8/8/8/r3K2R w K - 0 1", "e1e2", nil},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		m, err := ParseUCI(b, test.uci)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s in %s should give %v, not %v", test.uci, test.fen, test.want, err)
+		}
+		if err == nil && m.String() != strings.ToLower(test.uci) {
+			t.Errorf("%s in %s gave %s", test.uci, test.fen, m)
+		}
+	}
 }
 
 func TestUndoMove(t *testing.T) {
-	tests := map[string][]Move{
-		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1": []Move{
-			Move{0x14, 0x34, EMPTY},
-			Move{0x64, 0x44, EMPTY},
+	tests := map[string][][2]int{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1": {
+			{0x14, 0x34},
+			{0x64, 0x44},
 		},
-		"r1bqk1nr/pppp1ppp/2n5/1Bb1p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq -": []Move{
-			Move{0x04, 0x06, EMPTY},
+		"r1bqk1nr/pppp1ppp/2n5/1Bb1p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq -": {
+			{0x04, 0x06},
 		},
 		// e.p.
-		"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6": []Move{
-			Move{0x44, 0x53, EMPTY},
+		"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6": {
+			{0x44, 0x53},
 		},
 		// castling
-		"4k3/8/8/8/8/8/8/R3K3 w Q -": []Move{
-			Move{0x04, 0x02, EMPTY},
+		"4k3/8/8/8/8/8/8/R3K3 w Q -": {
+			{0x04, 0x02},
 		},
 	}
 
@@ -310,8 +404,8 @@ func TestUndoMove(t *testing.T) {
 		a := FromFEN(fen)
 		b := FromFEN(fen)
 
-		for _, move := range moves {
-			MakeMove(b, move)
+		for _, squares := range moves {
+			MakeMove(b, NewMove(b, squares[0], squares[1], EMPTY))
 		}
 
 		for i := 0; i < len(moves); i++ {
@@ -460,3 +554,173 @@ func TestSquareIndexToNotation(t *testing.T) {
 		}
 	}
 }
+
+func TestMoveClocks(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	moves := []string{"g1f3", "g8f6", "e2e4", "f6e4", "f1c4", "b8c6", "e1g1"}
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+		"rnbqkb1r/pppppppp/5n2/8/8/5N2/PPPPPPPP/RNBQKB1R w KQkq - 2 2",
+		"rnbqkb1r/pppppppp/5n2/8/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq e3 0 2",
+		"rnbqkb1r/pppppppp/8/8/4n3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 3",
+		"rnbqkb1r/pppppppp/8/8/2B1n3/5N2/PPPP1PPP/RNBQK2R b KQkq - 1 3",
+		"r1bqkb1r/pppppppp/2n5/8/2B1n3/5N2/PPPP1PPP/RNBQK2R w KQkq - 2 4",
+		"r1bqkb1r/pppppppp/2n5/8/2B1n3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 3 4",
+	}
+	for i, move := range moves {
+		MakeMoveFromNotation(b, move)
+		if fen := ToFEN(b); fen != fens[i] {
+			t.Errorf("After %s FEN should be\n%s not\n%s", move, fens[i], fen)
+		}
+	}
+	for i := len(moves) - 1; i > 0; i-- {
+		UndoMove(b)
+		if fen := ToFEN(b); fen != fens[i-1] {
+			t.Errorf("After undo FEN should be\n%s not\n%s", fens[i-1], fen)
+		}
+	}
+}
+
+func TestIsRepetition(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	if b.IsRepetition(2) {
+		t.Error("Initial position should not be a repetition")
+	}
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	for _, move := range shuffle {
+		MakeMoveFromNotation(b, move)
+	}
+	if !b.IsRepetition(2) || b.IsRepetition(3) {
+		t.Error("Initial position should have occurred exactly twice")
+	}
+	for _, move := range shuffle {
+		MakeMoveFromNotation(b, move)
+	}
+	if !b.IsRepetition(3) {
+		t.Error("Initial position should have occurred three times")
+	}
+	UndoMove(b)
+	if !b.IsRepetition(2) || b.IsRepetition(3) {
+		t.Error("Position after undo should have occurred exactly twice")
+	}
+
+	// Positions before a pawn move can't be repeated.
+	b = FromFEN(InitialPositionFEN)
+	for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "e2e4", "e7e5", "g1f3", "g8f6", "f3g1", "f6g8"} {
+		MakeMoveFromNotation(b, move)
+	}
+	if !b.IsRepetition(2) || b.IsRepetition(3) {
+		t.Error("Repetition count should stop at the last pawn move")
+	}
+}
+
+func TestMakeNullMove(t *testing.T) {
+	fen := "rnbqkbnr/ppp1pppp/8/8/2Pp4/8/PP1PPPPP/RNBQKBNR b KQkq c3 0 3"
+	b := FromFEN(fen)
+	MakeNullMove(b)
+	if after := ToFEN(b); after != "rnbqkbnr/ppp1pppp/8/8/2Pp4/8/PP1PPPPP/RNBQKBNR w KQkq - 1 4" {
+		t.Errorf("Position after null move should be white to move with no e.p. square, not %s", after)
+	}
+	if b.Hash() != b.FullZobristHash() {
+		t.Error("Hash should be updated by null move")
+	}
+	UndoNullMove(b)
+	if after := ToFEN(b); after != fen {
+		t.Errorf("Position after undoing null move should be %s, not %s", fen, after)
+	}
+	if b.Hash() != b.FullZobristHash() {
+		t.Error("Hash should be restored by undoing null move")
+	}
+	if len(b.moveHistory) != 0 {
+		t.Error("Move history should be empty after undoing null move")
+	}
+
+	// Real moves after a null move, as the search makes them, and then
+	// undoing everything back to the start.
+	MakeNullMove(b)
+	for _, move := range []string{"g1f3", "e7e5"} {
+		if err := MakeMoveFromNotation(b, move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := ToFEN(b); after != "rnbqkbnr/ppp2ppp/8/4p3/2Pp4/5N2/PP1PPPPP/RNBQKB1R w KQkq e6 0 5" {
+		t.Errorf("Position after a null move and two moves should be white to move with e.p. square e6, not %s", after)
+	}
+	if b.Hash() != b.FullZobristHash() {
+		t.Error("Hash should be right after moves following a null move")
+	}
+	UndoMove(b)
+	UndoMove(b)
+	UndoNullMove(b)
+	if after := ToFEN(b); after != fen {
+		t.Errorf("Position after undoing the moves and null move should be %s, not %s", fen, after)
+	}
+	if b.Hash() != b.FullZobristHash() {
+		t.Error("Hash should be restored by undoing the moves and null move")
+	}
+}
+
+func TestClone(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	for _, move := range []string{"g1f3", "g8f6", "f3g1"} {
+		MakeMoveFromNotation(b, move)
+	}
+	fen := ToFEN(b)
+	c := b.Clone()
+	if ToFEN(c) != fen || c.Hash() != b.Hash() {
+		t.Errorf("Clone should be %s, not %s", fen, ToFEN(c))
+	}
+
+	// Moves on the clone don't change the original, even once the clone's
+	// history has been undone and written over.
+	UndoMove(c)
+	MakeMoveFromNotation(c, "e2e4")
+	MakeMoveFromNotation(c, "e7e5")
+	if ToFEN(b) != fen {
+		t.Errorf("Original should still be %s, not %s", fen, ToFEN(b))
+	}
+	MakeMoveFromNotation(b, "f6g8")
+	if !b.IsRepetition(2) {
+		t.Errorf("Original should have repeated the initial position")
+	}
+	c = b.Clone()
+	for i := 0; i < 4; i++ {
+		UndoMove(b)
+	}
+	if ToFEN(b) != InitialPositionFEN {
+		t.Errorf("Undoing all moves on the original should give %s, not %s", InitialPositionFEN, ToFEN(b))
+	}
+
+	// The history is copied, so moves before cloning can be undone.
+	for i := 0; i < 4; i++ {
+		UndoMove(c)
+	}
+	if ToFEN(c) != InitialPositionFEN {
+		t.Errorf("Undoing all moves on the clone should give %s, not %s", InitialPositionFEN, ToFEN(c))
+	}
+}
+
+func TestPieces(t *testing.T) {
+	b := FromFEN("4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1")
+	MakeMoveFromNotation(b, "e5d6")
+	if got := b.Pieces(WHITE | PAWN).Squares(); len(got) != 1 || got[0] != NotationToSquareIndex("d6") {
+		t.Errorf("White pawns should be on d6, not %v", got)
+	}
+	if got := b.Pieces(BLACK | PAWN); got != 0 {
+		t.Errorf("Black pawn should have been captured, but got %v", got.Squares())
+	}
+	UndoMove(b)
+	if !b.Pieces(BLACK|PAWN).Has(NotationToSquareIndex("d5")) || !b.Pieces(WHITE|PAWN).Has(NotationToSquareIndex("e5")) {
+		t.Errorf("Pawns should be back on d5 and e5")
+	}
+
+	b = FromFEN("1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	MakeMoveFromNotation(b, "a7b8n")
+	if b.Pieces(WHITE|PAWN) != 0 || !b.Pieces(WHITE|KNIGHT).Has(NotationToSquareIndex("b8")) || b.Pieces(BLACK|ROOK) != 0 {
+		t.Errorf("Pawn should have captured the rook and become a knight")
+	}
+	UndoMove(b)
+	if b.Pieces(WHITE|KNIGHT) != 0 || b.Pieces(WHITE|PAWN).Count() != 1 || b.Pieces(BLACK|ROOK).Count() != 1 {
+		t.Errorf("Undoing the promotion should bring back the pawn and the rook")
+	}
+}
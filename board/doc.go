@@ -0,0 +1,16 @@
+// Package board represents chess positions and provides move generation,
+// evaluation and search.
+//
+// A Board is not safe for concurrent use. MakeMove, UndoMove and the other
+// functions which make moves change the board they are given, and so do the
+// searches and perft, which make and undo moves as they go even though the
+// board ends up as it started. Functions which only look at a position, such
+// as the move generators, IsLegal, GivesCheck, SEE, Evaluate and ToFEN, can
+// be called from several goroutines at once as long as none of them changes
+// the board. To work on a position in parallel, give each goroutine its own
+// copy from Board.Clone.
+//
+// The package level tables are set up when the package is initialised and
+// are only read after that, apart from InitZobristKeys, which must not be
+// called while other goroutines are using the package.
+package board
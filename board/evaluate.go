@@ -1,7 +1,5 @@
 package board
 
-import "fmt"
-
 var pieceValues = map[int]int{
 	PAWN:   100,
 	KNIGHT: 300,
@@ -154,79 +152,48 @@ var pieceSquarePawn = [2][64]int{
 
 // Evaluate returns a score for a position from the point of view of the side to move.
 func Evaluate(b *Board) int {
-	result := 0
-	blackMaterial := 0
-	whiteMaterial := 0
+	blackScore := 0
+	whiteScore := 0
 	sideToMove := BLACK
 	if b.whiteToMove {
 		sideToMove = WHITE
 	}
-	var kingPosition int
-	var opponentColour int
-	if sideToMove == WHITE {
-		kingPosition = b.whiteKing
-		opponentColour = BLACK
-	} else { // Assume calling code is correct to avoid extra if
-		kingPosition = b.blackKing
-		opponentColour = WHITE
-	}
 
 	// Test for checkmate.
-	if IsCheck(b, sideToMove) {
-		escapeSquareFound := false
-		for _, offset := range DIAGONALSANDLINES {
-			if square := kingPosition + offset; LegalSquareIndex(square) && !IsAttacked(b, square, opponentColour) {
-				escapeSquareFound = true
-				break
-			}
-		}
-		if !escapeSquareFound {
-			return -30000
-		}
+	if IsCheck(b, sideToMove) && !hasLegalMove(b) {
+		return -mateScore
 	}
 
-	for rank := 0; rank < 8; rank++ {
-		for file := 0; file < 8; file++ {
-			square := rank<<4 | file
-			if !LegalSquareIndex(square) {
-				fmt.Printf("Illegal square %X\n", square)
-				return result // TODO: Get rid of this test stuff
-			}
-			if b.squares[square] == EMPTY {
-				continue
-			}
-
-			pieceType := GetPieceType(b.squares[square])
-			value := pieceValues[pieceType]
-			colour := GetColour(b.squares[square])
-			if colour == WHITE {
-				whiteMaterial += value
-			} else {
-				blackMaterial += value
-			}
-
-			// Add piece square bonuses.
-			pieceSquareIndex := rank*8 + file
-			colourIndex := colour >> 3
-			switch pieceType {
-			case KNIGHT:
-				result += pieceSquareKnight[0][colourIndex][pieceSquareIndex]
-			case BISHOP:
-				result += pieceSquareBishop[0][colourIndex][pieceSquareIndex]
-			case QUEEN:
-				result += pieceSquareQueen[0][colourIndex][pieceSquareIndex]
-			case PAWN:
-				result += pieceSquarePawn[colourIndex][pieceSquareIndex]
-			}
+	for occupied := b.occupied(); occupied != 0; {
+		square := popSquare(&occupied)
+
+		pieceType := GetPieceType(b.squares[square])
+		value := pieceValues[pieceType]
+		colour := GetColour(b.squares[square])
+
+		// Add piece square bonuses.
+		pieceSquareIndex := squareTo64(square)
+		colourIndex := colour >> 3
+		switch pieceType {
+		case KNIGHT:
+			value += pieceSquareKnight[0][colourIndex][pieceSquareIndex]
+		case BISHOP:
+			value += pieceSquareBishop[0][colourIndex][pieceSquareIndex]
+		case QUEEN:
+			value += pieceSquareQueen[0][colourIndex][pieceSquareIndex]
+		case PAWN:
+			value += pieceSquarePawn[colourIndex][pieceSquareIndex]
+		}
 
+		if colour == WHITE {
+			whiteScore += value
+		} else {
+			blackScore += value
 		}
 	}
 
 	if b.whiteToMove {
-		result += whiteMaterial - blackMaterial
-	} else {
-		result += blackMaterial - whiteMaterial
+		return whiteScore - blackScore
 	}
-	return result
-
+	return blackScore - whiteScore
 }
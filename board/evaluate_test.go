@@ -0,0 +1,20 @@
+package board
+
+import "testing"
+
+func TestEvaluatePieceSquares(t *testing.T) {
+	// A knight in the centre is better than one in the corner, for whichever
+	// side it belongs to.
+	good := "4k3/8/8/3N4/8/8/8/4K3"
+	bad := "4k3/8/8/8/8/8/8/N3K3"
+	gain := func(sideToMove string) int {
+		return Evaluate(FromFEN(good+" "+sideToMove+" - - 0 1")) - Evaluate(FromFEN(bad+" "+sideToMove+" - - 0 1"))
+	}
+	white, black := gain("w"), gain("b")
+	if white <= 0 {
+		t.Errorf("Centralising the knight should be good for white to move, but changed the score by %d", white)
+	}
+	if black != -white {
+		t.Errorf("Centralising the knight should change the score for black to move by %d, not %d", -white, black)
+	}
+}
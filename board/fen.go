@@ -0,0 +1,228 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Errors reported by ParseFEN. They are always wrapped in a *FENError, so use
+// errors.Is to find out what was wrong with the input.
+var (
+	ErrFENFieldCount      = errors.New("wrong number of fields")
+	ErrFENRankCount       = errors.New("wrong number of ranks")
+	ErrFENRankLength      = errors.New("rank does not contain 8 squares")
+	ErrFENPiece           = errors.New("invalid piece letter")
+	ErrFENSideToMove      = errors.New("invalid side to move")
+	ErrFENCastling        = errors.New("invalid castling field")
+	ErrFENEnPassant       = errors.New("invalid en passant square")
+	ErrFENClock           = errors.New("invalid move counter")
+	ErrFENKingCount       = errors.New("each side must have exactly one king")
+	ErrFENPawnOnBackRank  = errors.New("pawn on first or eighth rank")
+	ErrFENOpponentInCheck = errors.New("side not to move is in check")
+	ErrFENCastlingRights  = errors.New("castling rights do not match king and rook positions")
+)
+
+// FENError describes why a FEN string was rejected.
+type FENError struct {
+	FEN    string
+	Err    error
+	Detail string
+}
+
+func (e *FENError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("invalid FEN %q: %s", e.FEN, e.Err)
+	}
+	return fmt.Sprintf("invalid FEN %q: %s: %s", e.FEN, e.Err, e.Detail)
+}
+
+func (e *FENError) Unwrap() error {
+	return e.Err
+}
+
+// ParseFEN parses a FEN string and checks that it describes a position that
+// could occur in a game. The half move and full move fields are optional, as
+// in EPD.
+func ParseFEN(fen string) (*Board, error) {
+	b, err := decodeFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPosition(b); err != nil {
+		return nil, &FENError{FEN: fen, Err: err.Err, Detail: err.Detail}
+	}
+	return b, nil
+}
+
+// decodeFEN parses the fields of a FEN string without checking that the
+// resulting position makes sense.
+func decodeFEN(fen string) (*Board, error) {
+	b := Board{ep: -1, fullMove: 1}
+	fail := func(err error, detail string) (*Board, error) {
+		return nil, &FENError{FEN: fen, Err: err, Detail: detail}
+	}
+
+	fenParts := strings.Fields(fen)
+	if len(fenParts) < 4 || len(fenParts) > 6 {
+		return fail(ErrFENFieldCount, fmt.Sprintf("got %d, want 4 to 6", len(fenParts)))
+	}
+
+	boardParts := strings.Split(fenParts[0], "/")
+	if len(boardParts) != 8 {
+		return fail(ErrFENRankCount, fmt.Sprintf("got %d", len(boardParts)))
+	}
+	for rank, line := range boardParts {
+		file := 0
+		for _, char := range line {
+			if char >= '1' && char <= '8' {
+				file += int(char - '0')
+				continue
+			}
+			if !strings.ContainsRune("pnbrqkPNBRQK", char) {
+				return fail(ErrFENPiece, strconv.QuoteRune(char))
+			}
+			if file < 8 {
+				piece := PieceFromNotation(char)
+				square := (7-rank)*16 + file
+				b.setPiece(square, piece)
+				if piece == WHITE|KING {
+					b.whiteKing = square
+				} else if piece == BLACK|KING {
+					b.blackKing = square
+				}
+			}
+			file++
+		}
+		if file != 8 {
+			return fail(ErrFENRankLength, fmt.Sprintf("rank %d has %d", 8-rank, file))
+		}
+	}
+
+	switch fenParts[1] {
+	case "w":
+		b.whiteToMove = true
+	case "b":
+		b.whiteToMove = false
+	default:
+		return fail(ErrFENSideToMove, fenParts[1])
+	}
+
+	castlingBits := map[rune]int{
+		'K': 8,
+		'Q': 4,
+		'k': 2,
+		'q': 1,
+	}
+	if fenParts[2] != "-" {
+		for _, letter := range fenParts[2] {
+			bit, ok := castlingBits[letter]
+			if !ok || b.castling&bit != 0 {
+				return fail(ErrFENCastling, fenParts[2])
+			}
+			b.castling |= bit
+		}
+	}
+
+	if fenParts[3] != "-" {
+		ep := fenParts[3]
+		if !isSquareNotation(ep) {
+			return fail(ErrFENEnPassant, ep)
+		}
+		b.ep = NotationToSquareIndex(ep)
+	}
+
+	if len(fenParts) > 4 {
+		halfMove, err := strconv.Atoi(fenParts[4])
+		if err != nil || halfMove < 0 {
+			return fail(ErrFENClock, "half move "+fenParts[4])
+		}
+		b.halfMove = halfMove
+	}
+
+	if len(fenParts) > 5 {
+		fullMove, err := strconv.Atoi(fenParts[5])
+		if err != nil || fullMove < 1 {
+			return fail(ErrFENClock, "full move "+fenParts[5])
+		}
+		b.fullMove = fullMove
+	}
+
+	b.CalculateZobristHash()
+
+	return &b, nil
+}
+
+// checkPosition checks that a decoded position is one that could arise in
+// a game. Only Err and Detail are filled in on the returned error.
+func checkPosition(b *Board) *FENError {
+	if err := checkPieces(b); err != nil {
+		return err
+	}
+	if IsCheck(b, GetOpponentColour(ColourToMove(b))) {
+		return &FENError{Err: ErrFENOpponentInCheck}
+	}
+	return checkRights(b)
+}
+
+// checkPieces checks that each side has one king and there are no pawns on
+// the first or last ranks.
+func checkPieces(b *Board) *FENError {
+	if pawns := (b.pieceBitboards[WHITE|PAWN] | b.pieceBitboards[BLACK|PAWN]) & (rank1 | rank8); pawns != 0 {
+		return &FENError{Err: ErrFENPawnOnBackRank, Detail: SquareIndexToNotation(bitboardSquare(pawns))}
+	}
+	whiteKings, blackKings := b.Pieces(WHITE|KING).Count(), b.Pieces(BLACK|KING).Count()
+	if whiteKings != 1 || blackKings != 1 {
+		return &FENError{Err: ErrFENKingCount, Detail: fmt.Sprintf("white %d, black %d", whiteKings, blackKings)}
+	}
+	return nil
+}
+
+// checkRights checks that the castling rights and en passant square match
+// the positions of the pieces.
+func checkRights(b *Board) *FENError {
+	castlingPieces := []struct {
+		bit  int
+		king int
+		rook int
+		kSq  int
+		rSq  int
+	}{
+		{8, WHITE | KING, WHITE | ROOK, 0x04, 0x07},
+		{4, WHITE | KING, WHITE | ROOK, 0x04, 0x00},
+		{2, BLACK | KING, BLACK | ROOK, 0x74, 0x77},
+		{1, BLACK | KING, BLACK | ROOK, 0x74, 0x70},
+	}
+	for _, c := range castlingPieces {
+		if b.castling&c.bit == 0 {
+			continue
+		}
+		if b.squares[c.kSq] != c.king || b.squares[c.rSq] != c.rook {
+			return &FENError{Err: ErrFENCastlingRights, Detail: castlingRightNotation(c.bit)}
+		}
+	}
+
+	if b.ep != -1 {
+		// The ep square must be directly behind a pawn that has just made
+		// a double move, with both squares it passed over empty.
+		epRank, behind, pawn := 0x50, S, BLACK|PAWN
+		if !b.whiteToMove {
+			epRank, behind, pawn = 0x20, N, WHITE|PAWN
+		}
+		if b.ep&0xF0 != epRank || b.squares[b.ep] != EMPTY || b.squares[b.ep-behind] != EMPTY || b.squares[b.ep+behind] != pawn {
+			return &FENError{Err: ErrFENEnPassant, Detail: SquareIndexToNotation(b.ep)}
+		}
+	}
+
+	return nil
+}
+
+func castlingRightNotation(bit int) string {
+	for i, symbol := range "KQkq" {
+		if bit == 1<<uint(3-i) {
+			return string(symbol)
+		}
+	}
+	return "-"
+}
@@ -0,0 +1,82 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFEN(t *testing.T) {
+	valid := []string{
+		InitialPositionFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w K -",
+	}
+	for _, fen := range valid {
+		b, err := ParseFEN(fen)
+		if err != nil {
+			t.Errorf("%s should be valid: %s", fen, err)
+			continue
+		}
+		if b.squares[b.whiteKing] != WHITE|KING || b.squares[b.blackKing] != BLACK|KING {
+			t.Errorf("Kings not set for %s", fen)
+		}
+	}
+
+	invalid := map[string]error{
+		"": ErrFENFieldCount,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w":                 ErrFENFieldCount,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 x":    ErrFENFieldCount,
+		"rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":        ErrFENRankCount,
+		"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":       ErrFENRankLength,
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":      ErrFENPiece,
+		"rnbqkbnr/pppppppp/54/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":     ErrFENRankLength,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPPP/RNBQKBNR w KQkq - 0 1":     ErrFENRankLength,
+		"rnbqkbnr/pppxpppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":      ErrFENPiece,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1":      ErrFENSideToMove,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1":      ErrFENCastling,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKkq - 0 1":      ErrFENCastling,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e9 0 1":     ErrFENEnPassant,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1":      ErrFENClock,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0":      ErrFENClock,
+		"rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1":        ErrFENKingCount,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBKKBNR w kq - 0 1":        ErrFENKingCount,
+		"rnbqkbnP/pppppppp/8/8/8/8/PPPPPPP1/RNBQKBNR w KQq - 0 1":       ErrFENPawnOnBackRank,
+		"4k3/8/8/8/8/8/8/4K2R w KQ - 0 1":                               ErrFENCastlingRights,
+		"4k3/8/8/8/8/8/8/3K3R w K - 0 1":                                ErrFENCastlingRights,
+		"4k3/8/8/8/8/8/8/4KR2 w K - 0 1":                                ErrFENCastlingRights,
+		"4k3/8/8/8/8/8/8/4K2R w k - 0 1":                                ErrFENCastlingRights,
+		"4k3/8/8/8/8/8/8/4R2K w - - 0 1":                                ErrFENOpponentInCheck,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e3 0 1":   ErrFENEnPassant,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq d3 0 1":   ErrFENEnPassant,
+		"rnbqkbnr/pppppppp/8/8/8/4P3/PPPP1PPP/RNBQKBNR b KQkq e3 0 1":   ErrFENEnPassant,
+		"rnbqkbnr/pppppppp/8/8/4P3/4N3/PPPP1PPP/RNBQKB1R b KQkq e3 0 1": ErrFENEnPassant,
+	}
+	for fen, want := range invalid {
+		b, err := ParseFEN(fen)
+		if err == nil {
+			t.Errorf("%q should be invalid", fen)
+			continue
+		}
+		if b != nil {
+			t.Errorf("%q should not return a board", fen)
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("%q should give %q, not %q", fen, want, err)
+		}
+		var fenErr *FENError
+		if !errors.As(err, &fenErr) || fenErr.FEN != fen {
+			t.Errorf("%q should give a *FENError containing the FEN", fen)
+		}
+	}
+}
+
+func TestFromFENPanicsOnMalformedInput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("FromFEN should panic on malformed input")
+		}
+	}()
+	FromFEN("rnbqkbnr/pppppppp w")
+}
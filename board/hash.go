@@ -13,6 +13,10 @@ type zobristKeybase struct {
 
 var ZobristKeys zobristKeybase
 
+func init() {
+	InitZobristKeys()
+}
+
 func InitZobristKeys() {
 	r := rand.New(rand.NewSource(27092014))
 	for piece := 1; piece < 7; piece++ {
@@ -20,7 +24,7 @@ func InitZobristKeys() {
 			if !LegalSquareIndex(square) {
 				continue
 			}
-			ZobristKeys.PiecePosition[piece][square] = r.Uint64()
+			ZobristKeys.PiecePosition[piece|WHITE][square] = r.Uint64()
 			ZobristKeys.PiecePosition[piece|BLACK][square] = r.Uint64()
 		}
 	}
@@ -33,23 +37,60 @@ func InitZobristKeys() {
 	}
 }
 
+// CalculateZobristHash sets the board's hash key from scratch. MakeMove and
+// UndoMove keep it up to date after that.
 func (b *Board) CalculateZobristHash() {
-	b.zobristKey = 0
-	for rank := 0; rank < 8; rank++ {
-		for file := 0; file < 8; file++ {
-			square := rank<<4 | file
-			b.zobristKey ^= ZobristKeys.PiecePosition[b.squares[square]][square]
-		}
+	b.zobristKey = b.FullZobristHash()
+}
+
+// FullZobristHash calculates the hash key of the position from scratch,
+// without using or changing the incrementally maintained key.
+func (b *Board) FullZobristHash() uint64 {
+	var key uint64
+	for occupied := b.occupied(); occupied != 0; {
+		square := popSquare(&occupied)
+		key ^= ZobristKeys.PiecePosition[b.squares[square]][square]
 	}
 	if b.whiteToMove {
-		b.zobristKey ^= ZobristKeys.WhiteToMove
+		key ^= ZobristKeys.WhiteToMove
 	}
-	for i := 0; i < 4; i++ {
-		if b.castling&(1<<uint(i)) != 0 {
-			b.zobristKey ^= ZobristKeys.Castling[i]
+	key ^= castlingZobristKey(b.castling)
+	key ^= epZobristKey(b)
+	return key
+}
+
+// Hash returns the Zobrist hash key of the current position.
+func (b *Board) Hash() uint64 {
+	return b.zobristKey
+}
+
+// epZobristKey returns the key for the e.p. square, or zero if there is no
+// pawn that could capture e.p. Otherwise positions that can only differ by
+// a double pawn push would never count as repetitions.
+func epZobristKey(b *Board) uint64 {
+	if b.ep <= 0 {
+		return 0
+	}
+	pawnSquare, pawn := b.ep+S, WHITE|PAWN
+	if !b.whiteToMove {
+		pawnSquare, pawn = b.ep+N, BLACK|PAWN
+	}
+	for _, side := range []int{pawnSquare + W, pawnSquare + E} {
+		if LegalSquareIndex(side) && b.squares[side] == pawn {
+			return ZobristKeys.EpFile[b.ep&7]
 		}
 	}
-	if b.ep > 0 {
-		b.zobristKey ^= ZobristKeys.EpFile[b.ep&7]
+	return 0
+}
+
+// castlingZobristKey returns the combined keys for the given castling bits.
+// XORing in the keys for old^new castling updates a hash incrementally.
+func castlingZobristKey(castling int) uint64 {
+	var key uint64
+	for i := 0; i < 4; i++ {
+		if castling&(1<<uint(i)) != 0 {
+			key ^= ZobristKeys.Castling[i]
+		}
 	}
+	return key
 }
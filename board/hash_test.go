@@ -14,15 +14,31 @@ func TestInitZobristKeys(t *testing.T) {
 		t.Errorf("key should be zero for empty piece")
 	}
 
+	// Every piece on every square needs its own key.
+	seen := map[uint64]bool{}
+	for _, colour := range []int{WHITE, BLACK} {
+		for pieceType := PAWN; pieceType <= KING; pieceType++ {
+			for square := 0; square < 128; square++ {
+				key := ZobristKeys.PiecePosition[colour|pieceType][square]
+				if !LegalSquareIndex(square) {
+					continue
+				}
+				if key == 0 || seen[key] {
+					t.Fatalf("%s on %s should have its own key", PieceToNotation(colour|pieceType), SquareIndexToNotation(square))
+				}
+				seen[key] = true
+			}
+		}
+	}
 }
 
 func TestCalculateZobristHash(t *testing.T) {
 
 	InitZobristKeys()
 
-	correct := map[string][]Move{
-		InitialPositionFEN:           []Move{{0x14, 0x34, 0}},
-		"4k2r/8/8/8/8/8/8/4K3 b k -": []Move{{0x74, 0x76, 0}},
+	correct := map[string][][2]int{
+		InitialPositionFEN:           {{0x14, 0x34}},
+		"4k2r/8/8/8/8/8/8/4K3 b k -": {{0x74, 0x76}},
 	}
 
 	for fen, moves := range correct {
@@ -33,7 +49,8 @@ func TestCalculateZobristHash(t *testing.T) {
 		}
 		initialPosKey := b.zobristKey
 
-		for _, move := range moves {
+		for _, squares := range moves {
+			move := NewMove(b, squares[0], squares[1], EMPTY)
 			MakeMove(b, move)
 
 			b.CalculateZobristHash()
@@ -61,3 +78,75 @@ func BenchmarkInitZobristKeys(b *testing.B) {
 		InitZobristKeys()
 	}
 }
+
+// hashPerft walks the move tree, checking the incremental hash against a
+// full recalculation at every node.
+func hashPerft(t *testing.T, b *Board, depth int) {
+	if b.Hash() != b.FullZobristHash() {
+		t.Fatalf("incremental hash differs from full hash in %s", ToFEN(b))
+	}
+	if depth == 0 {
+		return
+	}
+	if !IsCheck(b, ColourToMove(b)) {
+		key, fen := b.Hash(), ToFEN(b)
+		MakeNullMove(b)
+		if b.Hash() != b.FullZobristHash() {
+			t.Fatalf("incremental hash differs from full hash after null move in %s", fen)
+		}
+		UndoNullMove(b)
+		if b.Hash() != key || ToFEN(b) != fen {
+			t.Fatalf("position not restored after undoing null move in %s", fen)
+		}
+	}
+	for _, move := range GenerateLegalMoves(b) {
+		key := b.Hash()
+		MakeMove(b, move)
+		hashPerft(t, b, depth-1)
+		UndoMove(b)
+		if b.Hash() != key {
+			t.Fatalf("hash not restored after undoing %s in %s", move, ToFEN(b))
+		}
+	}
+}
+
+func TestIncrementalZobristHash(t *testing.T) {
+	fens := []string{
+		InitialPositionFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", // kiwipete
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1", // promotions
+		"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6 0 1",
+	}
+	for _, fen := range fens {
+		hashPerft(t, FromFEN(fen), 3)
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	a := FromFEN(InitialPositionFEN)
+	for _, move := range []string{"g1f3", "g8f6", "b1c3"} {
+		MakeMoveFromNotation(a, move)
+	}
+	b := FromFEN(InitialPositionFEN)
+	for _, move := range []string{"b1c3", "g8f6", "g1f3"} {
+		MakeMoveFromNotation(b, move)
+	}
+	if a.Hash() != b.Hash() {
+		t.Errorf("transposed positions should have the same hash")
+	}
+
+	// The e.p. square is only part of the key if a capture is possible.
+	c := FromFEN("rnbqkbnr/ppp1pppp/8/8/3p4/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	MakeMoveFromNotation(c, "e2e4")
+	d := FromFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if c.Hash() == d.Hash() {
+		t.Errorf("positions with different e.p. squares should have different hashes")
+	}
+	e := FromFEN(InitialPositionFEN)
+	MakeMoveFromNotation(e, "e2e4")
+	f := FromFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if e.Hash() != f.Hash() {
+		t.Errorf("e.p. square should not change the hash if no capture is possible")
+	}
+}
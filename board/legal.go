@@ -0,0 +1,304 @@
+package board
+
+// betweenBB holds the squares strictly between two squares on the same line
+// or diagonal, and lineBB the whole of the line or diagonal through both.
+// They are indexed by 64 square indexes, and empty if the squares don't line
+// up.
+var (
+	betweenBB [64][64]uint64
+	lineBB    [64][64]uint64
+)
+
+func init() {
+	for from := 0; from < 64; from++ {
+		for _, direction := range DIAGONALSANDLINES {
+			line := slidingAttacks(squareTo88(from), []int{direction, -direction}, 0) | 1<<uint(from)
+			var between uint64
+			for to := squareTo88(from) + direction; LegalSquareIndex(to); to += direction {
+				betweenBB[from][squareTo64(to)] = between
+				lineBB[from][squareTo64(to)] = line
+				between |= squareBB(to)
+			}
+		}
+	}
+}
+
+// between returns the squares strictly between two 0x88 squares.
+func between(from int, to int) uint64 {
+	return betweenBB[squareTo64(from)][squareTo64(to)]
+}
+
+// line returns the whole line or diagonal through two 0x88 squares.
+func line(from int, to int) uint64 {
+	return lineBB[squareTo64(from)][squareTo64(to)]
+}
+
+// attackersTo returns the pieces of the given colour attacking a square,
+// taking the occupied squares from the bitboard given rather than the board.
+func attackersTo(b *Board, square int, colour int, occupied uint64) uint64 {
+	square64 := squareTo64(square)
+	queens := b.pieceBitboards[colour|QUEEN]
+	result := pawnAttacks[GetOpponentColour(colour)>>3][square64] & b.pieceBitboards[colour|PAWN]
+	result |= knightAttacks[square64] & b.pieceBitboards[colour|KNIGHT]
+	result |= kingAttacks[square64] & b.pieceBitboards[colour|KING]
+	result |= bishopAttacks(square, occupied) & (b.pieceBitboards[colour|BISHOP] | queens)
+	result |= rookAttacks(square, occupied) & (b.pieceBitboards[colour|ROOK] | queens)
+	return result & occupied
+}
+
+// pinned returns the pieces of the given colour which can't move off the
+// line between their king and an opposing slider.
+func pinned(b *Board, colour int) uint64 {
+	var result uint64
+	pins, count := findPins(b, colour)
+	for _, pin := range pins[:count] {
+		result |= squareBB(pin.Pinned)
+	}
+	return result
+}
+
+// findPins finds the pieces of the given colour pinned to their king, and
+// the pieces pinning them. There can be at most one pin along each of the
+// eight lines from the king, so they fit in an array and nothing needs to
+// be allocated.
+func findPins(b *Board, colour int) ([8]Pin, int) {
+	var pins [8]Pin
+	count := 0
+	king := kingSquare(b, colour)
+	opponent := GetOpponentColour(colour)
+	queens := b.pieceBitboards[opponent|QUEEN]
+	snipers := bishopAttacks(king, 0)&(b.pieceBitboards[opponent|BISHOP]|queens) |
+		rookAttacks(king, 0)&(b.pieceBitboards[opponent|ROOK]|queens)
+	occupied := b.occupied()
+	for snipers != 0 {
+		sniper := popSquare(&snipers)
+		blockers := between(king, sniper) & occupied
+		if blockers == 0 || blockers&(blockers-1) != 0 || blockers&b.colourBitboards[colour>>3] == 0 {
+			continue
+		}
+		pins[count] = Pin{Pinned: bitboardSquare(blockers), Pinner: sniper}
+		count++
+	}
+	return pins, count
+}
+
+func kingSquare(b *Board, colour int) int {
+	if colour == WHITE {
+		return b.whiteKing
+	}
+	return b.blackKing
+}
+
+// GenerateLegalMoves generates only the legal moves for the position. Pins
+// and checks are worked out once up front, so this is much faster than
+// calling LegalMove on each of the moves from GenerateMoves.
+func GenerateLegalMoves(b *Board) []Move {
+	var list MoveList
+	GenerateLegalMovesInto(b, &list)
+	return append([]Move(nil), list.Moves()...)
+}
+
+// GenerateLegalMovesInto adds the legal moves for the position to the end
+// of the list.
+func GenerateLegalMovesInto(b *Board, list *MoveList) {
+	colour := ColourToMove(b)
+	opponent := GetOpponentColour(colour)
+	ownPieces := b.colourBitboards[colour>>3]
+	occupied := b.occupied()
+	king := kingSquare(b, colour)
+
+	// The king can't move to an attacked square, including one attacked
+	// through the square it's moving off.
+	targets := kingAttacks[squareTo64(king)] &^ ownPieces
+	withoutKing := occupied &^ squareBB(king)
+	for targets != 0 {
+		to := popSquare(&targets)
+		if attackersTo(b, to, opponent, withoutKing) == 0 {
+			list.Add(NewMove(b, king, to, EMPTY))
+		}
+	}
+
+	checkers := attackersTo(b, king, opponent, occupied)
+	if checkers&(checkers-1) != 0 {
+		// Double check, so only the king can move.
+		return
+	}
+
+	// Other pieces must capture or block a single checking piece.
+	checkMask := ^uint64(0)
+	if checkers != 0 {
+		checkMask = checkers | between(king, bitboardSquare(checkers))
+	} else {
+		if CastlingLegal(b, king, E) {
+			list.Add(newMove(king, king+2, kingCastleFlag))
+		}
+		if CastlingLegal(b, king, W) {
+			list.Add(newMove(king, king-2, queenCastleFlag))
+		}
+	}
+
+	pinnedPieces := pinned(b, colour)
+	pieces := ownPieces &^ squareBB(king)
+	for pieces != 0 {
+		from := popSquare(&pieces)
+		piece := b.squares[from]
+		mask := checkMask
+		if pinnedPieces&squareBB(from) != 0 {
+			mask &= line(king, from)
+		}
+
+		if GetPieceType(piece) != PAWN {
+			addMovesToSquares(b, from, pieceAttacks(piece, from, occupied)&^ownPieces&mask, list)
+			continue
+		}
+
+		targets := pawnTargets(b, from)
+		var epTarget uint64
+		if b.ep > 0 {
+			epTarget = targets & squareBB(b.ep)
+		}
+		targets &= mask &^ epTarget
+		if epTarget != 0 && legalEnPassant(b, from) {
+			targets |= epTarget
+		}
+		addPawnMoves(b, from, targets, list)
+	}
+}
+
+// legalEnPassant checks an en passant capture by the pawn on the given
+// square by trying it on the bitboards, as taking two pawns off the same
+// rank can reveal a check that the pins don't show.
+func legalEnPassant(b *Board, from int) bool {
+	colour := ColourToMove(b)
+	captured := from&0xF0 | b.ep&0x0F
+	occupied := b.occupied()&^squareBB(from)&^squareBB(captured) | squareBB(b.ep)
+	attackers := attackersTo(b, kingSquare(b, colour), GetOpponentColour(colour), occupied)
+	return attackers == 0
+}
+
+// IsPseudoLegal reports whether a move, e.g. from the hash table or a killer
+// move, could be generated by GenerateMoves in the current position. It
+// doesn't check whether the move would leave the king in check.
+func IsPseudoLegal(b *Board, m Move) bool {
+	colour := ColourToMove(b)
+	from, to := m.From(), m.To()
+	piece := b.squares[from]
+	if m == NullMove || piece == EMPTY || GetColour(piece) != colour {
+		return false
+	}
+	if b.squares[to] != EMPTY && GetColour(b.squares[to]) == colour {
+		return false
+	}
+	// The flags must be the ones the move would have in this position.
+	if NewMove(b, from, to, m.Promotion()) != m {
+		return false
+	}
+
+	switch GetPieceType(piece) {
+	case PAWN:
+		lastRank := squareBB(to)&(rank1|rank8) != 0
+		return pawnTargets(b, from)&squareBB(to) != 0 && m.IsPromotion() == lastRank
+	case KING:
+		switch m.flags() {
+		case kingCastleFlag:
+			return CastlingLegal(b, from, E)
+		case queenCastleFlag:
+			return CastlingLegal(b, from, W)
+		}
+	}
+	return !m.IsPromotion() && pieceAttacks(piece, from, b.occupied())&squareBB(to) != 0
+}
+
+// IsLegal reports whether a move is legal in the current position. Unlike
+// LegalMove it doesn't make the move, and the move can come from anywhere,
+// e.g. an opening book or user input.
+func IsLegal(b *Board, m Move) bool {
+	if !IsPseudoLegal(b, m) {
+		return false
+	}
+	colour := ColourToMove(b)
+	king := kingSquare(b, colour)
+	// Moves other than by the king must capture or block a single checking
+	// piece. En passant is checked in full by isLegal anyway.
+	if m.From() != king && !m.IsEnPassant() {
+		checkers := attackersTo(b, king, GetOpponentColour(colour), b.occupied())
+		if checkers&(checkers-1) != 0 {
+			return false
+		}
+		if checkers != 0 && (checkers|between(king, bitboardSquare(checkers)))&squareBB(m.To()) == 0 {
+			return false
+		}
+	}
+	return isLegal(b, m, pinned(b, colour))
+}
+
+// isLegal reports whether a pseudo-legal move leaves the king safe, given
+// the pinned pieces of the side to move. If the side to move is in check,
+// the move must be one that deals with the check, e.g. from GenerateEvasions.
+func isLegal(b *Board, m Move, pinnedPieces uint64) bool {
+	colour := ColourToMove(b)
+	king := kingSquare(b, colour)
+	from, to := m.From(), m.To()
+	if from == king {
+		if m.IsCastling() {
+			// Castling was checked when it was generated.
+			return true
+		}
+		withoutKing := b.occupied() &^ squareBB(king)
+		return attackersTo(b, to, GetOpponentColour(colour), withoutKing) == 0
+	}
+	if m.IsEnPassant() {
+		return legalEnPassant(b, from)
+	}
+	return pinnedPieces&squareBB(from) == 0 || line(king, from)&squareBB(to) != 0
+}
+
+// GivesCheck reports whether a pseudo-legal move would put the opponent in
+// check, without making it. This includes discovered checks, checks from the
+// rook when castling, and discovered checks along the rank of a pawn captured
+// en passant.
+func GivesCheck(b *Board, m Move) bool {
+	from, to := m.From(), m.To()
+	colour := GetColour(b.squares[from])
+	king := kingSquare(b, GetOpponentColour(colour))
+	piece := GetPieceType(b.squares[from])
+	if m.IsPromotion() {
+		piece = m.Promotion()
+	}
+
+	// The pieces of each kind once the move has been made.
+	occupied := b.occupied()&^squareBB(from) | squareBB(to)
+	queens := b.pieceBitboards[colour|QUEEN]
+	diagonals := (b.pieceBitboards[colour|BISHOP] | queens) &^ squareBB(from)
+	straights := (b.pieceBitboards[colour|ROOK] | queens) &^ squareBB(from)
+	switch piece {
+	case PAWN:
+		if pawnAttacks[colour>>3][squareTo64(to)]&squareBB(king) != 0 {
+			return true
+		}
+	case KNIGHT:
+		if knightAttacks[squareTo64(to)]&squareBB(king) != 0 {
+			return true
+		}
+	case BISHOP:
+		diagonals |= squareBB(to)
+	case ROOK:
+		straights |= squareBB(to)
+	case QUEEN:
+		diagonals |= squareBB(to)
+		straights |= squareBB(to)
+	}
+	switch {
+	case m.IsEnPassant():
+		occupied &^= squareBB(from&0xF0 | to&0x0F)
+	case m.IsCastling():
+		rookFrom, rookTo := to+1, from+1
+		if m.flags() == queenCastleFlag {
+			rookFrom, rookTo = to-2, from-1
+		}
+		occupied = occupied&^squareBB(rookFrom) | squareBB(rookTo)
+		straights = straights&^squareBB(rookFrom) | squareBB(rookTo)
+	}
+
+	return bishopAttacks(king, occupied)&diagonals != 0 || rookAttacks(king, occupied)&straights != 0
+}
@@ -0,0 +1,194 @@
+package board
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// filteredMoves returns the legal moves the slow way, by trying each
+// pseudo-legal move.
+func filteredMoves(b *Board) []string {
+	result := []string{}
+	for _, move := range GenerateMoves(b) {
+		if LegalMove(b, move) {
+			result = append(result, move.String())
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func moveStrings(moves []Move) []string {
+	result := make([]string, len(moves))
+	for i, move := range moves {
+		result[i] = move.String()
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestGenerateLegalMoves(t *testing.T) {
+	tests := []struct {
+		fen   string
+		count int
+	}{
+		{InitialPositionFEN, 20},
+		// Pinned rook can only move along the pin.
+		{"4k3/8/8/8/4r3/8/4R3/4K3 w - - 0 1", 6},
+		// Pinned bishop can only capture the pinning piece.
+		{"4k3/8/8/8/8/2q5/3B4/4K3 w - - 0 1", 5},
+		// Check from a knight, which can't be blocked, and no castling.
+		{"4k3/8/8/8/8/3n4/8/R3K2R w KQ - 0 1", 4},
+		// Check from a rook can be blocked.
+		{"4k3/4r3/8/8/8/8/3B4/R3K3 w Q - 0 1", 4},
+		// Double check, so only the king moves.
+		{"4k3/4r3/8/8/1b6/8/3N4/4K3 w - - 0 1", 3},
+		// Capturing e.p. would expose the king along the rank.
+		{"8/8/8/KPp4r/8/8/8/7k w - c6 0 1", 4},
+		// Capturing e.p. removes the checking pawn.
+		{"8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1", 9},
+		// The king can't step back along the line of a check.
+		{"4k3/8/8/8/8/8/8/r3K3 w - - 0 1", 3},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		moves := GenerateLegalMoves(b)
+		if len(moves) != test.count {
+			t.Errorf("%s should have %d legal moves, not %d: %v", test.fen, test.count, len(moves), moves)
+		}
+		if got, want := moveStrings(moves), filteredMoves(b); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: legal moves %v differ from filtered moves %v", test.fen, got, want)
+		}
+	}
+}
+
+func TestGenerateLegalMovesMatchesLegalMove(t *testing.T) {
+	for _, entry := range readPerftSuite(t) {
+		b := FromFEN(entry.fen)
+		for _, move := range GenerateLegalMoves(b) {
+			MakeMove(b, move)
+			got, want := moveStrings(GenerateLegalMoves(b)), filteredMoves(b)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: legal moves %v differ from filtered moves %v", ToFEN(b), got, want)
+			}
+			UndoMove(b)
+		}
+	}
+}
+
+func TestGenerateLegalMovesIntoAppends(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	var list MoveList
+	GenerateLegalMovesInto(b, &list)
+	GenerateLegalMovesInto(b, &list)
+	if list.Len() != 40 {
+		t.Errorf("List should have 40 moves after generating twice, not %d", list.Len())
+	}
+	if list.At(20) != list.At(0) {
+		t.Errorf("Second generation should start with %s, not %s", list.At(0), list.At(20))
+	}
+	list.Clear()
+	if list.Len() != 0 || len(list.Moves()) != 0 {
+		t.Errorf("List should be empty after Clear")
+	}
+}
+
+func TestGivesCheck(t *testing.T) {
+	tests := []struct {
+		fen   string
+		move  string
+		check bool
+	}{
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", true},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a7", false},
+		// Discovered check from the bishop behind the knight.
+		{"4k3/8/8/1N6/B7/8/8/4K3 w - - 0 1", "b5d4", true},
+		// Castling with the rook giving check.
+		{"5k2/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", true},
+		{"3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", "e1c1", true},
+		// Capturing e.p. opens the rank to the king.
+		{"8/8/8/R2pP2k/8/8/8/4K3 w - d6 0 1", "e5d6", true},
+		// Promoting to a knight gives check, but to a queen doesn't.
+		{"8/4P3/8/5k2/8/8/8/4K3 w - - 0 1", "e7e8n", false},
+		{"8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", "e7e8n", true},
+		{"8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", "e7e8q", false},
+		// A pawn push stays on the file, but a capture leaves it.
+		{"4k3/8/8/8/8/5p2/4P3/4R1K1 w - - 0 1", "e2e4", false},
+		{"4k3/8/8/8/8/5p2/4P3/4R1K1 w - - 0 1", "e2f3", true},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		m := mustFindMove(t, b, test.move)
+		if got := GivesCheck(b, m); got != test.check {
+			t.Errorf("%s in %s should give check: %t", test.move, test.fen, test.check)
+		}
+	}
+}
+
+// givesCheckPerft checks GivesCheck against making each move at every node
+// of a perft run.
+func givesCheckPerft(t *testing.T, b *Board, depth int) {
+	if depth == 0 {
+		return
+	}
+	for _, move := range GenerateLegalMoves(b) {
+		want := GivesCheck(b, move)
+		MakeMove(b, move)
+		if got := IsCheck(b, ColourToMove(b)); got != want {
+			UndoMove(b)
+			t.Fatalf("%s: %s should give check: %t", ToFEN(b), move, got)
+		}
+		givesCheckPerft(t, b, depth-1)
+		UndoMove(b)
+	}
+}
+
+func TestGivesCheckMatchesMakeMove(t *testing.T) {
+	depth := 3
+	if testing.Short() {
+		depth = 2
+	}
+	for _, entry := range readPerftSuite(t) {
+		givesCheckPerft(t, FromFEN(entry.fen), depth)
+	}
+}
+
+func TestIsLegal(t *testing.T) {
+	forEachPerftPosition(t, func(b *Board) {
+		legal := map[Move]bool{}
+		for _, move := range GenerateLegalMoves(b) {
+			legal[move] = true
+		}
+		found := 0
+		for from := 0; from < 128; from++ {
+			if !LegalSquareIndex(from) {
+				continue
+			}
+			for to := 0; to < 128; to++ {
+				if !LegalSquareIndex(to) || to == from {
+					continue
+				}
+				for _, promotion := range []int{EMPTY, KNIGHT, QUEEN} {
+					m := NewMove(b, from, to, promotion)
+					if IsLegal(b, m) != legal[m] {
+						t.Fatalf("%s: %s should be legal: %t", ToFEN(b), m, legal[m])
+					}
+					if legal[m] {
+						found++
+					}
+				}
+			}
+		}
+		// Underpromotions to a bishop or rook aren't tried.
+		want := 0
+		for move := range legal {
+			if move.Promotion() != BISHOP && move.Promotion() != ROOK {
+				want++
+			}
+		}
+		if found != want {
+			t.Fatalf("%s: found %d legal moves, not %d", ToFEN(b), found, want)
+		}
+	})
+}
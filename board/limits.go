@@ -0,0 +1,143 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrSearchLimit is reported by ParseSearchLimits, wrapped in a
+// *SearchLimitError.
+var ErrSearchLimit = errors.New("invalid search limit")
+
+// SearchLimitError describes a search limit that couldn't be parsed.
+type SearchLimitError struct {
+	Name  string
+	Value string
+}
+
+func (e *SearchLimitError) Error() string {
+	return fmt.Sprintf("%s: %s %q", ErrSearchLimit, e.Name, e.Value)
+}
+
+func (e *SearchLimitError) Unwrap() error {
+	return ErrSearchLimit
+}
+
+// SearchLimits says when a search should stop, as given to the UCI go
+// command. Zero values mean no limit, and a search with no limits at all
+// carries on until it is stopped or reaches the maximum depth.
+type SearchLimits struct {
+	WhiteTime time.Duration // Time left on the clocks.
+	BlackTime time.Duration
+	WhiteInc  time.Duration // Time added after each move.
+	BlackInc  time.Duration
+	MovesToGo int           // Moves until the next time control.
+	MoveTime  time.Duration // Exact time to spend on this move.
+	Depth     int
+	Nodes     int
+	Mate      int // Search for a mate in this many moves.
+	Infinite  bool
+}
+
+// ParseSearchLimits parses the arguments of a UCI go command, e.g.
+// "wtime 60000 btime 60000 movestogo 40". Arguments it doesn't know, such as
+// ponder and searchmoves, are skipped.
+func ParseSearchLimits(args []string) (SearchLimits, error) {
+	var limits SearchLimits
+	durations := map[string]*time.Duration{
+		"wtime":    &limits.WhiteTime,
+		"btime":    &limits.BlackTime,
+		"winc":     &limits.WhiteInc,
+		"binc":     &limits.BlackInc,
+		"movetime": &limits.MoveTime,
+	}
+	counts := map[string]*int{
+		"movestogo": &limits.MovesToGo,
+		"depth":     &limits.Depth,
+		"nodes":     &limits.Nodes,
+		"mate":      &limits.Mate,
+	}
+	for i := 0; i < len(args); i++ {
+		name := args[i]
+		if name == "infinite" {
+			limits.Infinite = true
+			continue
+		}
+		duration, isDuration := durations[name]
+		count, isCount := counts[name]
+		if !isDuration && !isCount {
+			continue
+		}
+		if i+1 == len(args) {
+			return SearchLimits{}, &SearchLimitError{Name: name}
+		}
+		i++
+		value, err := strconv.Atoi(args[i])
+		// The clocks can go below zero if a move was made late.
+		if err != nil || (value < 0 && name != "wtime" && name != "btime") {
+			return SearchLimits{}, &SearchLimitError{Name: name, Value: args[i]}
+		}
+		if isDuration {
+			*duration = time.Duration(value) * time.Millisecond
+		} else {
+			*count = value
+		}
+	}
+	return limits, nil
+}
+
+// moveOverhead is time kept back from the clock for sending the move and
+// any delays in the GUI.
+const moveOverhead = 30 * time.Millisecond
+
+// defaultMovesToGo is how many more moves the time left is shared between
+// when there's no time control to aim for.
+const defaultMovesToGo = 30
+
+// timeBudget works out how long the given colour should spend on its move.
+// The search doesn't start another depth once the soft budget is used up,
+// and stops part way through one when the hard budget is. Both are zero if
+// there is no time limit.
+func (l SearchLimits) timeBudget(colour int) (soft, hard time.Duration) {
+	if l.MoveTime > 0 {
+		hard = l.MoveTime - moveOverhead
+		if hard < time.Millisecond {
+			hard = time.Millisecond
+		}
+		return hard, hard
+	}
+
+	remaining, inc := l.WhiteTime, l.WhiteInc
+	if colour == BLACK {
+		remaining, inc = l.BlackTime, l.BlackInc
+	}
+	if remaining == 0 && inc == 0 {
+		return 0, 0
+	}
+	available := remaining - moveOverhead
+	if available < time.Millisecond {
+		available = time.Millisecond
+	}
+	movesToGo := l.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+
+	// Aim for a fair share of the time left, but allow up to four times that
+	// to finish a depth, as long as it leaves enough for later moves.
+	soft = available/time.Duration(movesToGo) + inc*3/4
+	hard = soft * 4
+	maxHard := available / 2
+	if movesToGo == 1 {
+		maxHard = available
+	}
+	if hard > maxHard {
+		hard = maxHard
+	}
+	if soft > hard {
+		soft = hard
+	}
+	return soft, hard
+}
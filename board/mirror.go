@@ -0,0 +1,54 @@
+package board
+
+// Mirror returns the position with the colours swapped and the board turned
+// upside down, so that white's pieces on the first rank become black's on
+// the eighth. The side to move, castling rights and en passant square are
+// swapped to match, so the mirrored position should have the same
+// evaluation and the same moves, flipped. The move history isn't kept.
+func (b *Board) Mirror() *Board {
+	return b.transform(0x70, true)
+}
+
+// FlipFiles returns the position reflected from left to right, so that
+// pieces on the a file end up on the h file. Castling rights are dropped as
+// they make no sense once the kings and rooks have changed sides. The move
+// history isn't kept.
+func (b *Board) FlipFiles() *Board {
+	return b.transform(0x07, false)
+}
+
+// transform returns a copy of the board with every square XORed with mask,
+// swapping the colours as well if swapColours is set.
+func (b *Board) transform(mask int, swapColours bool) *Board {
+	result := &Board{
+		whiteToMove: b.whiteToMove,
+		ep:          -1,
+		fullMove:    b.fullMove,
+		halfMove:    b.halfMove,
+	}
+	for occupied := b.occupied(); occupied != 0; {
+		square := popSquare(&occupied)
+		piece := b.squares[square]
+		if swapColours {
+			piece ^= WHITE
+		}
+		result.setPiece(square^mask, piece)
+		if GetPieceType(piece) == KING {
+			if GetColour(piece) == WHITE {
+				result.whiteKing = square ^ mask
+			} else {
+				result.blackKing = square ^ mask
+			}
+		}
+	}
+	if b.ep >= 0 {
+		result.ep = b.ep ^ mask
+	}
+	if swapColours {
+		result.whiteToMove = !b.whiteToMove
+		// KQ and kq swap places.
+		result.castling = b.castling>>2 | b.castling&3<<2
+	}
+	result.CalculateZobristHash()
+	return result
+}
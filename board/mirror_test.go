@@ -0,0 +1,55 @@
+package board
+
+import "testing"
+
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		fen      string
+		mirrored string
+		flipped  string
+	}{
+		{
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w Kq - 0 1",
+			"r3k2r/pppbbppp/2n2q1P/1P2p3/3pn3/BN2PNP1/P1PPQPB1/R3K2R b Qk - 0 1",
+			"r2k3r/1bpqpp1p/1pnp2nb/3NP3/3P2p1/p1Q2N2/PPPBBPPP/R2K3R w - - 0 1",
+		},
+		{
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			"rnbqkbnr/pppp1ppp/8/8/3PpP2/8/PPP1P1PP/RNBQKBNR b KQkq f3 0 3",
+			"rnbkqbnr/pp1p1ppp/8/2pPp3/8/8/PPP1PPPP/RNBKQBNR w - c6 0 3",
+		},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		if got := ToFEN(b.Mirror()); got != test.mirrored {
+			t.Errorf("%s mirrored should be %s, not %s", test.fen, test.mirrored, got)
+		}
+		if got := ToFEN(b.FlipFiles()); got != test.flipped {
+			t.Errorf("%s flipped should be %s, not %s", test.fen, test.flipped, got)
+		}
+		if got := ToFEN(b.Mirror().Mirror()); got != test.fen {
+			t.Errorf("%s mirrored twice should be unchanged, not %s", test.fen, got)
+		}
+	}
+}
+
+// Mirrored positions should have the same moves, flipped, and the same
+// evaluation. This catches mistakes in the piece square tables, where the
+// tables for black should be those for white upside down.
+func TestMirrorSymmetry(t *testing.T) {
+	forEachPerftPosition(t, func(b *Board) {
+		mirrored := b.Mirror()
+		if mirrored.Hash() != mirrored.FullZobristHash() {
+			t.Fatalf("%s: mirrored hash is wrong", ToFEN(b))
+		}
+		if got, want := len(GenerateLegalMoves(mirrored)), len(GenerateLegalMoves(b)); got != want {
+			t.Fatalf("%s: mirrored position has %d legal moves, not %d", ToFEN(b), got, want)
+		}
+		if got, want := Evaluate(mirrored), Evaluate(b); got != want {
+			t.Fatalf("%s: mirrored position evaluates to %d, not %d", ToFEN(b), got, want)
+		}
+		if got, want := Evaluate(b.FlipFiles()), Evaluate(b); got != want {
+			t.Fatalf("%s: flipped position evaluates to %d, not %d", ToFEN(b), got, want)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Move is a move packed into 16 bits. The low 12 bits hold the from and to
+// squares as 64 square indexes, and the top 4 are flags saying what kind of
+// move it is, so that MakeMove doesn't have to work it out again.
+type Move uint16
+
+// NullMove is the zero Move. It is used where there is no move, e.g. before
+// a search has found one, and is never a legal move.
+const NullMove Move = 0
+
+// The flags stored in the top 4 bits of a Move.
+const (
+	quietFlag       = 0
+	doublePushFlag  = 1
+	kingCastleFlag  = 2
+	queenCastleFlag = 3
+	captureFlag     = 4
+	enPassantFlag   = 5
+	// Promotions have promotionFlag plus the promoted piece type less
+	// KNIGHT, and captureFlag as well if they capture.
+	promotionFlag = 8
+)
+
+func newMove(from int, to int, flags int) Move {
+	return Move(squareTo64(from) | squareTo64(to)<<6 | flags<<12)
+}
+
+// NewMove creates the move from one square to another in the given
+// position, working out what kind of move it is from the pieces on the
+// board. The promotion is the piece type to promote to, or EMPTY.
+func NewMove(b *Board, from int, to int, promotion int) Move {
+	flags := quietFlag
+	if b.squares[to] != EMPTY {
+		flags = captureFlag
+	}
+	switch GetPieceType(b.squares[from]) {
+	case PAWN:
+		if to == b.ep && to&0x0F != from&0x0F {
+			flags = enPassantFlag
+		} else if to-from == 2*N || to-from == 2*S {
+			flags = doublePushFlag
+		}
+	case KING:
+		if to-from == 2 {
+			flags = kingCastleFlag
+		} else if from-to == 2 {
+			flags = queenCastleFlag
+		}
+	}
+	if promotion != EMPTY {
+		flags |= promotionFlag | (GetPieceType(promotion) - KNIGHT)
+	}
+	return newMove(from, to, flags)
+}
+
+// From returns the 0x88 square the move is from.
+func (m Move) From() int {
+	return squareTo88(int(m & 0x3F))
+}
+
+// To returns the 0x88 square the move is to.
+func (m Move) To() int {
+	return squareTo88(int(m >> 6 & 0x3F))
+}
+
+func (m Move) flags() int {
+	return int(m >> 12)
+}
+
+// Promotion returns the piece type a pawn is promoted to, or EMPTY if the
+// move is not a promotion.
+func (m Move) Promotion() int {
+	if !m.IsPromotion() {
+		return EMPTY
+	}
+	return m.flags()&3 + KNIGHT
+}
+
+// IsPromotion reports whether the move promotes a pawn.
+func (m Move) IsPromotion() bool {
+	return m.flags()&promotionFlag != 0
+}
+
+// IsCapture reports whether the move captures a piece, including en passant.
+func (m Move) IsCapture() bool {
+	return m.flags()&captureFlag != 0
+}
+
+// IsEnPassant reports whether the move is a pawn capturing en passant.
+func (m Move) IsEnPassant() bool {
+	return m.flags() == enPassantFlag
+}
+
+// IsDoublePush reports whether the move is a pawn moving two squares.
+func (m Move) IsDoublePush() bool {
+	return m.flags() == doublePushFlag
+}
+
+// IsCastling reports whether the move is castling, on either side.
+func (m Move) IsCastling() bool {
+	return m.flags() == kingCastleFlag || m.flags() == queenCastleFlag
+}
+
+// String returns the move in UCI format, e.g. e2e4 or a7a8q, or 0000 for
+// NullMove.
+func (m Move) String() string {
+	if m == NullMove {
+		return "0000"
+	}
+	result := SquareIndexToNotation(m.From()) + SquareIndexToNotation(m.To())
+	if m.IsPromotion() {
+		result += PieceToNotation(m.Promotion())
+	}
+	return result
+}
+
+// Errors reported by ParseUCI, wrapped in a *UCIMoveError.
+var (
+	ErrUCISyntax  = errors.New("not a valid move")
+	ErrUCIIllegal = errors.New("illegal move")
+)
+
+// UCIMoveError describes why a move in UCI format was rejected.
+type UCIMoveError struct {
+	UCI string
+	Err error
+}
+
+func (e *UCIMoveError) Error() string {
+	return fmt.Sprintf("%s: %q", e.Err, e.UCI)
+}
+
+func (e *UCIMoveError) Unwrap() error {
+	return e.Err
+}
+
+// ParseUCI finds the legal move described by a move in UCI format, e.g. e2e4
+// or a7a8q. The promotion piece may be in either case.
+func ParseUCI(b *Board, uci string) (Move, error) {
+	if len(uci) < 4 || len(uci) > 5 || !isSquareNotation(uci[:2]) || !isSquareNotation(uci[2:4]) {
+		return NullMove, &UCIMoveError{UCI: uci, Err: ErrUCISyntax}
+	}
+	promotion := EMPTY
+	if len(uci) == 5 {
+		if !strings.ContainsRune("nbrqNBRQ", rune(uci[4])) {
+			return NullMove, &UCIMoveError{UCI: uci, Err: ErrUCISyntax}
+		}
+		promotion = GetPieceType(PieceFromNotation(rune(uci[4])))
+	}
+	m := NewMove(b, NotationToSquareIndex(uci[:2]), NotationToSquareIndex(uci[2:4]), promotion)
+	if !IsLegal(b, m) {
+		return NullMove, &UCIMoveError{UCI: uci, Err: ErrUCIIllegal}
+	}
+	return m, nil
+}
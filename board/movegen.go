@@ -0,0 +1,115 @@
+package board
+
+// The staged generators split the pseudo-legal moves into captures and
+// quiet moves, so that a search can try captures first and often never
+// needs the quiet moves. Promotions to a queen are counted as captures, as
+// they change the material balance as much as one does.
+
+// GenerateCaptures adds the pseudo-legal captures to the list, including
+// en passant, promotions which capture, and promotions to a queen.
+func GenerateCaptures(b *Board, list *MoveList) {
+	colour := ColourToMove(b)
+	enemies := b.colourBitboards[GetOpponentColour(colour)>>3]
+	addPieceMoves(b, colour, enemies, list)
+
+	pawns := b.pieceBitboards[colour|PAWN]
+	for pawns != 0 {
+		from := popSquare(&pawns)
+		targets := pawnTargets(b, from)
+		captures := targets & pawnAttacks[colour>>3][squareTo64(from)]
+		addPawnMoves(b, from, captures, list)
+		if pushes := targets &^ captures & (rank1 | rank8); pushes != 0 {
+			list.Add(NewMove(b, from, popSquare(&pushes), QUEEN))
+		}
+	}
+}
+
+// GenerateQuiets adds the pseudo-legal moves which are not generated by
+// GenerateCaptures to the list, including castling and underpromotions.
+func GenerateQuiets(b *Board, list *MoveList) {
+	colour := ColourToMove(b)
+	empty := ^b.occupied()
+	addPieceMoves(b, colour, empty, list)
+
+	king := kingSquare(b, colour)
+	if CastlingLegal(b, king, E) {
+		list.Add(newMove(king, king+2, kingCastleFlag))
+	}
+	if CastlingLegal(b, king, W) {
+		list.Add(newMove(king, king-2, queenCastleFlag))
+	}
+
+	pawns := b.pieceBitboards[colour|PAWN]
+	for pawns != 0 {
+		from := popSquare(&pawns)
+		pushes := pawnTargets(b, from) &^ pawnAttacks[colour>>3][squareTo64(from)]
+		if pushes&(rank1|rank8) == 0 {
+			addPawnMoves(b, from, pushes, list)
+			continue
+		}
+		for _, piece := range PROMOTIONPIECES[1:] {
+			list.Add(NewMove(b, from, bitboardSquare(pushes), piece))
+		}
+	}
+}
+
+// GenerateEvasions adds moves which get the side to move out of check to the
+// list. King moves are all legal, but moves by other pieces may not be
+// legal if they are pinned. The side to move must be in check.
+func GenerateEvasions(b *Board, list *MoveList) {
+	colour := ColourToMove(b)
+	opponent := GetOpponentColour(colour)
+	ownPieces := b.colourBitboards[colour>>3]
+	occupied := b.occupied()
+	king := kingSquare(b, colour)
+
+	targets := kingAttacks[squareTo64(king)] &^ ownPieces
+	withoutKing := occupied &^ squareBB(king)
+	for targets != 0 {
+		to := popSquare(&targets)
+		if attackersTo(b, to, opponent, withoutKing) == 0 {
+			list.Add(NewMove(b, king, to, EMPTY))
+		}
+	}
+
+	checkers := attackersTo(b, king, opponent, occupied)
+	if checkers&(checkers-1) != 0 {
+		return
+	}
+	checker := bitboardSquare(checkers)
+	mask := checkers | between(king, checker)
+
+	pieces := ownPieces &^ b.pieceBitboards[colour|PAWN] &^ squareBB(king)
+	for pieces != 0 {
+		from := popSquare(&pieces)
+		addMovesToSquares(b, from, pieceAttacks(b.squares[from], from, occupied)&mask, list)
+	}
+
+	pawns := b.pieceBitboards[colour|PAWN]
+	for pawns != 0 {
+		from := popSquare(&pawns)
+		targets := pawnTargets(b, from)
+		evasions := targets & mask
+		// Capturing e.p. removes a checking pawn from a different square.
+		if b.ep > 0 && checker == (from&0xF0|b.ep&0x0F) {
+			evasions |= targets & squareBB(b.ep)
+		}
+		addPawnMoves(b, from, evasions, list)
+	}
+}
+
+// addPieceMoves adds the moves of the knights, bishops, rooks, queens and
+// king of the given colour to squares in targets.
+func addPieceMoves(b *Board, colour int, targets uint64, list *MoveList) {
+	pieces := b.colourBitboards[colour>>3] &^ b.pieceBitboards[colour|PAWN]
+	occupied := b.occupied()
+	for pieces != 0 {
+		from := popSquare(&pieces)
+		addMovesToSquares(b, from, pieceAttacks(b.squares[from], from, occupied)&targets, list)
+	}
+}
+
+// bitboardSquare returns the 0x88 square of the lowest set bit.
+func bitboardSquare(bb uint64) int {
+	return popSquare(&bb)
+}
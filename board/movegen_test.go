@@ -0,0 +1,91 @@
+package board
+
+import (
+	"reflect"
+	"testing"
+)
+
+// forEachPerftPosition calls f for each position in the perft suite and
+// each position one move on from them.
+func forEachPerftPosition(t *testing.T, f func(b *Board)) {
+	for _, entry := range readPerftSuite(t) {
+		b := FromFEN(entry.fen)
+		f(b)
+		for _, move := range GenerateLegalMoves(b) {
+			MakeMove(b, move)
+			f(b)
+			UndoMove(b)
+		}
+	}
+}
+
+func TestCapturesAndQuietsMakeAllMoves(t *testing.T) {
+	forEachPerftPosition(t, func(b *Board) {
+		var list MoveList
+		GenerateCaptures(b, &list)
+		captures := list.Len()
+		GenerateQuiets(b, &list)
+		got, want := moveStrings(list.Moves()), moveStrings(GenerateMoves(b))
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: captures and quiets %v differ from all moves %v", ToFEN(b), got, want)
+		}
+		for i, move := range list.Moves() {
+			if isQuiet(move) != (i >= captures) {
+				t.Fatalf("%s: %s is in the wrong generator", ToFEN(b), move)
+			}
+		}
+	})
+}
+
+func TestGenerateEvasions(t *testing.T) {
+	positions := 0
+	forEachPerftPosition(t, func(b *Board) {
+		if !IsCheck(b, ColourToMove(b)) {
+			return
+		}
+		positions++
+		var list MoveList
+		GenerateEvasions(b, &list)
+		legal := []Move{}
+		pinnedPieces := pinned(b, ColourToMove(b))
+		for _, move := range list.Moves() {
+			if isLegal(b, move, pinnedPieces) {
+				legal = append(legal, move)
+			}
+		}
+		if got, want := moveStrings(legal), moveStrings(GenerateLegalMoves(b)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: evasions %v differ from legal moves %v", ToFEN(b), got, want)
+		}
+	})
+	if positions == 0 {
+		t.Error("No positions in check were tested")
+	}
+}
+
+func TestIsPseudoLegal(t *testing.T) {
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	all := GenerateMoves(b)
+	for _, move := range all {
+		if !IsPseudoLegal(b, move) {
+			t.Errorf("%s should be pseudo-legal", move)
+		}
+	}
+	for _, uci := range []string{"e2e1", "a1a3", "e5e7", "b2b4", "d5c6", "e1c2", "a2a3q", "c3b5q", "e8g8"} {
+		promotion := EMPTY
+		if len(uci) > 4 {
+			promotion = GetPieceType(PieceFromNotation(rune(uci[4])))
+		}
+		m := NewMove(b, NotationToSquareIndex(uci[:2]), NotationToSquareIndex(uci[2:4]), promotion)
+		if IsPseudoLegal(b, m) {
+			t.Errorf("%s should not be pseudo-legal", uci)
+		}
+	}
+	// Moves with the wrong flags for the position aren't pseudo-legal either.
+	a2, a3 := NotationToSquareIndex("a2"), NotationToSquareIndex("a3")
+	if IsPseudoLegal(b, newMove(a2, a3, captureFlag)) {
+		t.Error("a2a3 as a capture should not be pseudo-legal")
+	}
+	if IsPseudoLegal(b, NullMove) {
+		t.Error("NullMove should not be pseudo-legal")
+	}
+}
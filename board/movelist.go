@@ -0,0 +1,39 @@
+package board
+
+// MaxMoves is the most moves a MoveList can hold, which is more than there
+// are in any reachable position.
+const MaxMoves = 256
+
+// MoveList is a fixed size list of moves. Move generators add to the end of
+// it in place, so a list can be reused without allocating any memory.
+type MoveList struct {
+	moves [MaxMoves]Move
+	count int
+}
+
+// Add adds a move to the end of the list.
+func (l *MoveList) Add(m Move) {
+	l.moves[l.count] = m
+	l.count++
+}
+
+// Len returns the number of moves in the list.
+func (l *MoveList) Len() int {
+	return l.count
+}
+
+// At returns the move at the given index.
+func (l *MoveList) At(i int) Move {
+	return l.moves[i]
+}
+
+// Moves returns the moves in the list. The slice shares the list's storage,
+// so it is only valid until the list is next changed.
+func (l *MoveList) Moves() []Move {
+	return l.moves[:l.count]
+}
+
+// Clear empties the list so that it can be reused.
+func (l *MoveList) Clear() {
+	l.count = 0
+}
@@ -0,0 +1,124 @@
+package board
+
+// The search tries the moves most likely to cause a cutoff first, as
+// alpha-beta only saves work when the best move comes early. The hash move
+// comes first, then captures by MVV-LVA with those losing material by SEE
+// left until last, then the killer moves, then the other quiet moves by
+// their history.
+
+// maxHistory bounds the history scores. Each update moves a score part of
+// the way towards it, so that they can't overflow and recent results count
+// for more than old ones.
+const maxHistory = 1 << 14
+
+// counterMoveBonus is added to the history score of the counter move, so
+// that it comes before the other quiet moves.
+const counterMoveBonus = maxHistory + 1
+
+// History remembers which quiet moves caused cutoffs in earlier searches, so
+// that they can be tried sooner. It keeps a score for each move by its
+// colour and squares, and the move which last refuted each move, known as
+// its counter move.
+//
+// A History can be kept between searches of the same game, and should be
+// cleared for a new game. It is not safe for concurrent use.
+type History struct {
+	butterfly    [2][64][64]int // By colour >> 3, from and to square.
+	counterMoves [15][64]Move   // By the piece which moved and where to.
+}
+
+// Clear forgets everything, e.g. before a new game.
+func (h *History) Clear() {
+	*h = History{}
+}
+
+// age halves the scores, so that a new search takes more notice of what it
+// finds itself than of what earlier ones did.
+func (h *History) age() {
+	for colour := range h.butterfly {
+		for from := range h.butterfly[colour] {
+			for to := range h.butterfly[colour][from] {
+				h.butterfly[colour][from][to] /= 2
+			}
+		}
+	}
+}
+
+// score returns the history score of a quiet move.
+func (h *History) score(b *Board, m Move) int {
+	return h.butterfly[ColourToMove(b)>>3][squareTo64(m.From())][squareTo64(m.To())]
+}
+
+// counterMove returns the move which last refuted the move just made, or
+// NullMove if there isn't one.
+func (h *History) counterMove(b *Board) Move {
+	if len(b.moveHistory) == 0 {
+		return NullMove
+	}
+	last := b.moveHistory[len(b.moveHistory)-1].move
+	if last == NullMove {
+		return NullMove
+	}
+	return h.counterMoves[b.squares[last.To()]][squareTo64(last.To())]
+}
+
+// update records that a quiet move caused a cutoff at the given depth, and
+// that the quiet moves tried before it didn't. The board must be in the
+// position the moves were made from.
+func (h *History) update(b *Board, best Move, tried []Move, depth int) {
+	bonus := depth * depth
+	h.add(b, best, bonus)
+	for _, m := range tried {
+		if m != best {
+			h.add(b, m, -bonus)
+		}
+	}
+
+	if len(b.moveHistory) == 0 {
+		return
+	}
+	if last := b.moveHistory[len(b.moveHistory)-1].move; last != NullMove {
+		h.counterMoves[b.squares[last.To()]][squareTo64(last.To())] = best
+	}
+}
+
+func (h *History) add(b *Board, m Move, bonus int) {
+	entry := &h.butterfly[ColourToMove(b)>>3][squareTo64(m.From())][squareTo64(m.To())]
+	magnitude := bonus
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	*entry += bonus - *entry*magnitude/maxHistory
+}
+
+// sortMVVLVA sorts captures so that the most valuable victims come first,
+// and of those the ones taken by the least valuable attackers, as they are
+// the most likely to win material. The lists are short enough for an
+// insertion sort.
+func sortMVVLVA(b *Board, moves []Move) {
+	for i := 1; i < len(moves); i++ {
+		move := moves[i]
+		score := mvvLVA(b, move)
+		j := i
+		for ; j > 0 && mvvLVA(b, moves[j-1]) < score; j-- {
+			moves[j] = moves[j-1]
+		}
+		moves[j] = move
+	}
+}
+
+// mvvLVA scores a move for sortMVVLVA. Quiet moves score 0.
+func mvvLVA(b *Board, m Move) int {
+	victim := GetPieceType(b.squares[m.To()])
+	if m.IsEnPassant() {
+		victim = PAWN
+	}
+	if victim == EMPTY && !m.IsPromotion() {
+		return 0
+	}
+	score := pieceValues[victim]*8 - pieceValues[GetPieceType(b.squares[m.From()])]/100
+	if m.IsPromotion() {
+		score += (pieceValues[m.Promotion()] - pieceValues[PAWN]) * 8
+	}
+	return score
+}
@@ -0,0 +1,218 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/micaherne/unidexter-go/tt"
+)
+
+func TestHistory(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	best := mustFindMove(t, b, "g1f3")
+	tried := []Move{mustFindMove(t, b, "a2a3"), mustFindMove(t, b, "h2h3")}
+	h := &History{}
+	h.update(b, best, append(tried, best), 4)
+	if got := h.score(b, best); got != 16 {
+		t.Errorf("Cutoff at depth 4 should score 16, not %d", got)
+	}
+	if got := h.score(b, tried[0]); got != -16 {
+		t.Errorf("Move tried before the cutoff should score -16, not %d", got)
+	}
+
+	// Scores approach the maximum but never reach it.
+	for i := 0; i < 1000; i++ {
+		h.update(b, best, nil, 20)
+	}
+	if got := h.score(b, best); got <= maxHistory/2 || got > maxHistory {
+		t.Errorf("Score should be close to %d, not %d", maxHistory, got)
+	}
+	score := h.score(b, best)
+	h.age()
+	if got := h.score(b, best); got != score/2 {
+		t.Errorf("Aging should halve the score to %d, not %d", score/2, got)
+	}
+
+	// The other colour has its own scores.
+	MakeMove(b, mustFindMove(t, b, "e2e4"))
+	if got := h.score(b, newMove(best.From(), best.To(), 0)); got != 0 {
+		t.Errorf("Black should not have a score for g1f3, not %d", got)
+	}
+
+	// The counter move is looked up by the move just made.
+	counter := mustFindMove(t, b, "e7e5")
+	h.update(b, counter, nil, 1)
+	if got := h.counterMove(b); got != counter {
+		t.Errorf("Counter move to e2e4 should be %s, not %s", counter, got)
+	}
+	MakeNullMove(b)
+	if got := h.counterMove(b); got != NullMove {
+		t.Errorf("A null move should not have a counter move, not %s", got)
+	}
+
+	h.Clear()
+	UndoNullMove(b)
+	if got := h.counterMove(b); got != NullMove {
+		t.Errorf("Clear should forget the counter moves, but found %s", got)
+	}
+}
+
+func TestMovePickerUsesHistory(t *testing.T) {
+	b := FromFEN("4k3/8/2p5/3p4/4PN2/3r4/2P5/3QK3 w - - 0 1")
+	MakeMove(b, NewMove(b, NotationToSquareIndex("e1"), NotationToSquareIndex("f1"), EMPTY))
+	MakeMove(b, NewMove(b, NotationToSquareIndex("e8"), NotationToSquareIndex("e7"), EMPTY))
+	h := &History{}
+	counter := mustFindMove(t, b, "d1e2")
+	liked := mustFindMove(t, b, "c2c3")
+	disliked := mustFindMove(t, b, "f1g1")
+	h.update(b, liked, []Move{disliked}, 10)
+	// The last move to cause a cutoff is the counter move.
+	h.update(b, counter, nil, 1)
+
+	moves := pickAll(NewMovePicker(b, NullMove, [2]Move{}, h))
+	var quiets []Move
+	for _, m := range moves {
+		if isQuiet(m) {
+			quiets = append(quiets, m)
+		}
+	}
+	if len(quiets) < 3 || quiets[0] != counter || quiets[1] != liked {
+		t.Errorf("Quiet moves should start with %s then %s, not %v", counter, liked, quiets)
+	}
+	if last := quiets[len(quiets)-1]; last != disliked {
+		t.Errorf("Quiet moves should end with %s, not %s", disliked, last)
+	}
+
+	// Captures come in MVV-LVA order, so the rook is taken by the pawn.
+	if moves[0].String() != "c2d3" {
+		t.Errorf("Picker should start with the pawn taking the rook, not %s", moves[0])
+	}
+}
+
+// orderingPositions are searched to compare how well each part of the move
+// ordering works.
+var orderingPositions = []string{
+	InitialPositionFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+}
+
+// scanOrder returns the legal moves in the order they are generated, as
+// the search did before it had any move ordering.
+type scanOrder struct {
+	moves MoveList
+	index int
+}
+
+func (o *scanOrder) Next() (Move, bool) {
+	if o.index == o.moves.Len() {
+		return NullMove, false
+	}
+	o.index++
+	return o.moves.At(o.index - 1), true
+}
+
+// ordering is a way of ordering the moves, turning on more of the search's
+// move ordering in turn.
+type ordering struct {
+	name     string
+	hashMove bool // Whether to use a transposition table for hash moves.
+	// hook returns the orderHook for a searcher, or nil to use all of the
+	// ordering, including the history.
+	hook func(s *searcher) func(b *Board, ply int, hashMove Move) moveIterator
+}
+
+var orderings = []ordering{
+	{"scan order", false, func(s *searcher) func(*Board, int, Move) moveIterator {
+		var lists [maxPly]scanOrder
+		return func(b *Board, ply int, hashMove Move) moveIterator {
+			o := &lists[ply]
+			o.moves.Clear()
+			o.index = 0
+			GenerateLegalMovesInto(b, &o.moves)
+			return o
+		}
+	}},
+	{"captures", false, pickerHook(false, false)},
+	{"hash move", true, pickerHook(true, false)},
+	{"killers", true, pickerHook(true, true)},
+	{"history", true, nil},
+}
+
+// pickerHook returns a hook ordering the moves with a MovePicker without
+// the history, and with or without the hash move and killers.
+func pickerHook(hashMove, killers bool) func(s *searcher) func(*Board, int, Move) moveIterator {
+	return func(s *searcher) func(*Board, int, Move) moveIterator {
+		return func(b *Board, ply int, hash Move) moveIterator {
+			p := &s.pickers[ply]
+			if !hashMove {
+				hash = NullMove
+			}
+			var k [2]Move
+			if killers {
+				k = s.killers[ply]
+			}
+			p.Init(b, hash, k, nil)
+			return p
+		}
+	}
+}
+
+// orderingNodes returns the nodes needed to search all of the
+// orderingPositions to the given depth with the given ordering.
+func orderingNodes(depth int, o ordering) int {
+	nodes := 0
+	for _, fen := range orderingPositions {
+		s := &searcher{limits: SearchLimits{Depth: depth}}
+		if o.hashMove {
+			s.tt = tt.New(1, MateThreshold)
+		}
+		if o.hook != nil {
+			s.orderHook = o.hook(s)
+		} else {
+			s.history = &History{}
+		}
+		s.iterate(FromFEN(fen), nil)
+		nodes += s.nodes
+	}
+	return nodes
+}
+
+// Only the whole ordering is checked against scan order here. The parts of
+// it are compared by BenchmarkMoveOrdering and BenchmarkQuietOrdering.
+func TestMoveOrderingSavesNodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Searching takes a while")
+	}
+	scan := orderingNodes(3, orderings[0])
+	all := orderingNodes(3, orderings[len(orderings)-1])
+	if all > scan/4 {
+		t.Errorf("Move ordering should save at least three quarters of the nodes, but needed %d rather than %d", all, scan)
+	}
+}
+
+// BenchmarkMoveOrdering compares each part of the move ordering at depth 4,
+// which is as deep as scan order can search in reasonable time.
+func BenchmarkMoveOrdering(b *testing.B) {
+	benchmarkOrderings(b, 4, orderings)
+}
+
+// BenchmarkQuietOrdering compares the killers with the history and counter
+// moves at depth 6. At depth 4 they make no difference, but by depth 6
+// they save around 5% of the nodes.
+func BenchmarkQuietOrdering(b *testing.B) {
+	benchmarkOrderings(b, 6, orderings[len(orderings)-2:])
+}
+
+func benchmarkOrderings(b *testing.B, depth int, orderings []ordering) {
+	for _, o := range orderings {
+		b.Run(o.name, func(b *testing.B) {
+			nodes := 0
+			for i := 0; i < b.N; i++ {
+				nodes = orderingNodes(depth, o)
+			}
+			b.ReportMetric(float64(nodes), "nodes/op")
+		})
+	}
+}
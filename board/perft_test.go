@@ -0,0 +1,98 @@
+package board
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// perftSuiteEntry is a line of the perft suite: a position and its node
+// counts at increasing depths.
+type perftSuiteEntry struct {
+	fen    string
+	counts []int
+}
+
+// readPerftSuite reads the perft suite used by the perft tool. It's parsed
+// by hand here as the epd package depends on this one.
+func readPerftSuite(t testing.TB) []perftSuiteEntry {
+	content, err := ioutil.ReadFile("../perft/perftsuite.epd")
+	if err != nil {
+		t.Skip(err)
+	}
+	var result []perftSuiteEntry
+	for _, line := range strings.Split(string(content), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ";")
+		if len(parts) < 2 {
+			continue
+		}
+		entry := perftSuiteEntry{fen: strings.TrimSpace(parts[0])}
+		for _, part := range parts[1:] {
+			count, err := strconv.Atoi(strings.Fields(part)[1])
+			if err != nil {
+				t.Fatalf("Invalid count in %s", line)
+			}
+			entry.counts = append(entry.counts, count)
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func perft(b *Board, depth int) int {
+	return perftLists(b, make([]MoveList, depth))
+}
+
+// perftLists counts the leaf nodes to the depth given by the number of move
+// lists, using one list for each ply.
+func perftLists(b *Board, lists []MoveList) int {
+	if len(lists) == 0 {
+		return 1
+	}
+	moves := &lists[0]
+	moves.Clear()
+	GenerateLegalMovesInto(b, moves)
+	nodes := 0
+	for _, move := range moves.Moves() {
+		MakeMove(b, move)
+		nodes += perftLists(b, lists[1:])
+		UndoMove(b)
+	}
+	return nodes
+}
+
+func TestPerftSuite(t *testing.T) {
+	depth := 3
+	if testing.Short() {
+		depth = 2
+	}
+	for _, entry := range readPerftSuite(t) {
+		b := FromFEN(entry.fen)
+		for i := 1; i <= depth && i <= len(entry.counts); i++ {
+			if nodes := perft(b, i); nodes != entry.counts[i-1] {
+				t.Errorf("Perft(%d) of %s should be %d, not %d", i, entry.fen, entry.counts[i-1], nodes)
+			}
+		}
+	}
+}
+
+func TestPerftDoesNotAllocate(t *testing.T) {
+	if validateMoves {
+		t.Skip("Validating moves allocates")
+	}
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	lists := make([]MoveList, 2)
+	if allocs := testing.AllocsPerRun(10, func() { perftLists(b, lists) }); allocs != 0 {
+		t.Errorf("Perft should not allocate, but made %v allocations", allocs)
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	board := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	lists := make([]MoveList, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		perftLists(board, lists)
+	}
+}
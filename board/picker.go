@@ -0,0 +1,202 @@
+package board
+
+// The stages a MovePicker goes through, in order. When in check it skips
+// straight to the evasion stages.
+const (
+	stageHashMove = iota
+	stageGenerateCaptures
+	stageGoodCaptures
+	stageKillers
+	stageGenerateQuiets
+	stageQuiets
+	stageBadCaptures
+	stageDone
+	stageGenerateEvasions
+	stageEvasions
+)
+
+// MovePicker returns the legal moves in a position one at a time, starting
+// with those most likely to be good: the hash move, captures which don't
+// lose material by MVV-LVA, killer moves, quiet moves by their history and
+// lastly captures which do lose material. Moves are only generated when the
+// stage that needs them is reached, so a cutoff on an early move saves
+// generating the rest, and each is only picked out from those left when it
+// is needed, so the rest needn't be sorted.
+//
+// A MovePicker can be reused for another position by calling Init again,
+// so a search can keep one for each ply rather than allocating new ones.
+type MovePicker struct {
+	b           *Board
+	hashMove    Move
+	killers     [2]Move
+	history     *History
+	counterMove Move
+	pinned      uint64
+	stage       int
+	index       int
+	moves       MoveList
+	scores      [MaxMoves]int // The order scores of the moves.
+	badCaptures MoveList
+}
+
+// NewMovePicker creates a MovePicker for the position. The hash move and
+// killers may be empty moves, or moves which aren't legal in the position,
+// in which case they are ignored. The history orders the quiet moves, and
+// may be nil to leave them in the order they are generated.
+func NewMovePicker(b *Board, hashMove Move, killers [2]Move, history *History) *MovePicker {
+	p := &MovePicker{}
+	p.Init(b, hashMove, killers, history)
+	return p
+}
+
+// Init sets up the picker to return the moves for a new position.
+func (p *MovePicker) Init(b *Board, hashMove Move, killers [2]Move, history *History) {
+	p.b = b
+	p.hashMove = hashMove
+	p.killers = killers
+	p.history = history
+	p.counterMove = NullMove
+	if history != nil {
+		p.counterMove = history.counterMove(b)
+	}
+	p.pinned = pinned(b, ColourToMove(b))
+	p.index = 0
+	p.moves.Clear()
+	p.badCaptures.Clear()
+	p.stage = stageHashMove
+	if IsCheck(b, ColourToMove(b)) {
+		p.stage = stageGenerateEvasions
+	}
+}
+
+// Next returns the next move, or false if there are no more.
+func (p *MovePicker) Next() (Move, bool) {
+	for {
+		switch p.stage {
+		case stageHashMove:
+			p.stage++
+			if p.hashMove != NullMove && IsPseudoLegal(p.b, p.hashMove) && isLegal(p.b, p.hashMove, p.pinned) {
+				return p.hashMove, true
+			}
+
+		case stageGenerateCaptures:
+			p.moves.Clear()
+			GenerateCaptures(p.b, &p.moves)
+			for i, m := range p.moves.Moves() {
+				p.scores[i] = mvvLVA(p.b, m)
+			}
+			p.index = 0
+			p.stage++
+
+		case stageGoodCaptures:
+			for p.index < p.moves.Len() {
+				p.selectBest()
+				m := p.moves.At(p.index)
+				p.index++
+				if m == p.hashMove || !isLegal(p.b, m, p.pinned) {
+					continue
+				}
+				if !SEEGE(p.b, m, 0) {
+					p.badCaptures.Add(m)
+					continue
+				}
+				return m, true
+			}
+			p.index = 0
+			p.stage++
+
+		case stageKillers:
+			for p.index < len(p.killers) {
+				m := p.killers[p.index]
+				p.index++
+				if m == NullMove || m == p.hashMove || !isQuiet(m) {
+					continue
+				}
+				if IsPseudoLegal(p.b, m) && isLegal(p.b, m, p.pinned) {
+					return m, true
+				}
+			}
+			p.stage++
+
+		case stageGenerateQuiets:
+			p.moves.Clear()
+			GenerateQuiets(p.b, &p.moves)
+			if p.history != nil {
+				for i, m := range p.moves.Moves() {
+					p.scores[i] = p.history.score(p.b, m)
+					if m == p.counterMove {
+						p.scores[i] += counterMoveBonus
+					}
+				}
+			}
+			p.index = 0
+			p.stage++
+
+		case stageQuiets:
+			for p.index < p.moves.Len() {
+				if p.history != nil {
+					p.selectBest()
+				}
+				m := p.moves.At(p.index)
+				p.index++
+				if m == p.hashMove || m == p.killers[0] || m == p.killers[1] || !isLegal(p.b, m, p.pinned) {
+					continue
+				}
+				return m, true
+			}
+			p.index = 0
+			p.stage++
+
+		case stageBadCaptures:
+			if p.index < p.badCaptures.Len() {
+				p.index++
+				return p.badCaptures.At(p.index - 1), true
+			}
+			p.stage = stageDone
+
+		case stageGenerateEvasions:
+			p.moves.Clear()
+			GenerateEvasions(p.b, &p.moves)
+			// Try the hash move first if it is one of the evasions.
+			for i, m := range p.moves.Moves() {
+				if m == p.hashMove {
+					p.moves.moves[0], p.moves.moves[i] = m, p.moves.moves[0]
+					break
+				}
+			}
+			p.index = 0
+			p.stage++
+
+		case stageEvasions:
+			for p.index < p.moves.Len() {
+				m := p.moves.At(p.index)
+				p.index++
+				if isLegal(p.b, m, p.pinned) {
+					return m, true
+				}
+			}
+			p.stage = stageDone
+
+		default:
+			return NullMove, false
+		}
+	}
+}
+
+// selectBest moves the best scoring of the moves left to the current index.
+func (p *MovePicker) selectBest() {
+	best := p.index
+	for i := p.index + 1; i < p.moves.Len(); i++ {
+		if p.scores[i] > p.scores[best] {
+			best = i
+		}
+	}
+	p.moves.moves[p.index], p.moves.moves[best] = p.moves.moves[best], p.moves.moves[p.index]
+	p.scores[p.index], p.scores[best] = p.scores[best], p.scores[p.index]
+}
+
+// isQuiet reports whether a move would be generated by GenerateQuiets
+// rather than GenerateCaptures.
+func isQuiet(m Move) bool {
+	return !m.IsCapture() && m.Promotion() != QUEEN
+}
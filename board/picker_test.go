@@ -0,0 +1,65 @@
+package board
+
+import (
+	"reflect"
+	"testing"
+)
+
+func pickAll(p *MovePicker) []Move {
+	var result []Move
+	for m, ok := p.Next(); ok; m, ok = p.Next() {
+		result = append(result, m)
+	}
+	return result
+}
+
+func TestMovePickerReturnsLegalMoves(t *testing.T) {
+	p := &MovePicker{}
+	forEachPerftPosition(t, func(b *Board) {
+		p.Init(b, NullMove, [2]Move{}, nil)
+		got, want := moveStrings(pickAll(p)), moveStrings(GenerateLegalMoves(b))
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: picked moves %v differ from legal moves %v", ToFEN(b), got, want)
+		}
+	})
+}
+
+func TestMovePickerOrder(t *testing.T) {
+	// The knight taking the pawn on d5 is the only capture that loses
+	// material, as the pawn is defended.
+	b := FromFEN("4k3/8/2p5/3p4/4PN2/3r4/2P5/3QK3 w - - 0 1")
+	hashMove := mustFindMove(t, b, "e1f2")
+	killer := mustFindMove(t, b, "d1e2")
+	// The other killer is a move for the wrong side, so should be ignored.
+	wrongSide := NewMove(b, NotationToSquareIndex("c6"), NotationToSquareIndex("c5"), EMPTY)
+	moves := pickAll(NewMovePicker(b, hashMove, [2]Move{killer, wrongSide}, nil))
+
+	if got, want := moveStrings(moves), moveStrings(GenerateLegalMoves(b)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Picked moves %v differ from legal moves %v", got, want)
+	}
+	if moves[0] != hashMove {
+		t.Errorf("Picker should start with the hash move, not %s", moves[0])
+	}
+	// Order within the good captures isn't important.
+	if got := moveStrings(moves[1:5]); !reflect.DeepEqual(got, []string{"c2d3", "d1d3", "e4d5", "f4d3"}) {
+		t.Errorf("Picker should return good captures after the hash move, not %v", got)
+	}
+	if moves[5] != killer {
+		t.Errorf("Picker should return the killer after good captures, not %s", moves[5])
+	}
+	if last := moves[len(moves)-1].String(); last != "f4d5" {
+		t.Errorf("Picker should return the bad capture last, not %s", last)
+	}
+}
+
+func TestMovePickerInCheck(t *testing.T) {
+	b := FromFEN("4k3/8/8/8/8/8/4r3/R3K3 w Q - 0 1")
+	hashMove := mustFindMove(t, b, "e1e2")
+	moves := pickAll(NewMovePicker(b, hashMove, [2]Move{}, nil))
+	if len(moves) == 0 || moves[0] != hashMove {
+		t.Errorf("Picker should start with the hash move when in check, not %v", moves)
+	}
+	if got, want := moveStrings(moves), moveStrings(GenerateLegalMoves(b)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Picked moves %v differ from legal moves %v", got, want)
+	}
+}
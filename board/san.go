@@ -0,0 +1,203 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors reported by ParseSAN, wrapped in a *SANError.
+var (
+	ErrSANSyntax    = errors.New("not a valid move")
+	ErrSANIllegal   = errors.New("illegal move")
+	ErrSANAmbiguous = errors.New("ambiguous move")
+)
+
+// SANError describes why a move in standard algebraic notation was rejected.
+type SANError struct {
+	SAN string
+	Err error
+}
+
+func (e *SANError) Error() string {
+	return fmt.Sprintf("%s: %q", e.Err, e.SAN)
+}
+
+func (e *SANError) Unwrap() error {
+	return e.Err
+}
+
+// ParseSAN finds the legal move described by a move in standard algebraic
+// notation, e.g. Nbxd7+. It accepts common variations such as 0-0 for O-O,
+// captures without an x, promotions without an = and trailing annotations.
+func ParseSAN(b *Board, san string) (Move, error) {
+	fail := func(err error) (Move, error) {
+		return NullMove, &SANError{SAN: san, Err: err}
+	}
+
+	s := strings.TrimRight(strings.TrimSpace(san), "+#!? ")
+	s = strings.TrimRight(strings.TrimSuffix(s, "e.p."), "+#!? ")
+
+	moves := GenerateLegalMoves(b)
+
+	castlingOffset := 0
+	switch s {
+	case "O-O", "0-0", "o-o":
+		castlingOffset = 2
+	case "O-O-O", "0-0-0", "o-o-o":
+		castlingOffset = -2
+	}
+	if castlingOffset != 0 {
+		for _, move := range moves {
+			if GetPieceType(b.squares[move.From()]) == KING && move.To()-move.From() == castlingOffset {
+				return move, nil
+			}
+		}
+		return fail(ErrSANIllegal)
+	}
+
+	if s == "" {
+		return fail(ErrSANSyntax)
+	}
+
+	piece := PAWN
+	if strings.IndexByte("KQRBN", s[0]) >= 0 {
+		piece = GetPieceType(PieceFromNotation(rune(s[0])))
+		s = s[1:]
+	}
+
+	promotion := EMPTY
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		if i != len(s)-2 || strings.IndexByte("QRBNqrbn", s[i+1]) < 0 {
+			return fail(ErrSANSyntax)
+		}
+		promotion = GetPieceType(PieceFromNotation(rune(s[i+1])))
+		s = s[:i]
+	} else if piece == PAWN && len(s) > 0 && strings.IndexByte("QRBNqrbn", s[len(s)-1]) >= 0 {
+		promotion = GetPieceType(PieceFromNotation(rune(s[len(s)-1])))
+		s = s[:len(s)-1]
+	}
+	if promotion != EMPTY && piece != PAWN {
+		return fail(ErrSANSyntax)
+	}
+
+	if len(s) < 2 || !isSquareNotation(s[len(s)-2:]) {
+		return fail(ErrSANSyntax)
+	}
+	to := NotationToSquareIndex(s[len(s)-2:])
+	s = s[:len(s)-2]
+
+	// Whatever is left is the square the piece moves from, or part of it,
+	// possibly with a capture or long algebraic separator.
+	fromFile, fromRank := -1, -1
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'h' && fromFile == -1 && fromRank == -1:
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8' && fromRank == -1:
+			fromRank = int(c - '1')
+		case c == 'x' || c == ':' || c == '-':
+		default:
+			return fail(ErrSANSyntax)
+		}
+	}
+
+	var result Move
+	found := 0
+	for _, move := range moves {
+		if move.To() != to || GetPieceType(b.squares[move.From()]) != piece || move.Promotion() != promotion {
+			continue
+		}
+		if fromFile != -1 && move.From()&0x0F != fromFile {
+			continue
+		}
+		if fromRank != -1 && move.From()>>4 != fromRank {
+			continue
+		}
+		result = move
+		found++
+	}
+	switch found {
+	case 0:
+		return fail(ErrSANIllegal)
+	case 1:
+		return result, nil
+	}
+	return fail(ErrSANAmbiguous)
+}
+
+// FormatSAN returns the standard algebraic notation for a move, which must be
+// legal in the position given. The board is used to check whether the move
+// gives check, but is left unchanged.
+func FormatSAN(b *Board, m Move) string {
+	movingPiece := GetPieceType(b.squares[m.From()])
+	var result strings.Builder
+
+	if m.IsCastling() && m.To() > m.From() {
+		result.WriteString("O-O")
+	} else if m.IsCastling() {
+		result.WriteString("O-O-O")
+	} else {
+		if movingPiece == PAWN {
+			if m.IsCapture() {
+				result.WriteString(SquareIndexToNotation(m.From())[:1])
+			}
+		} else {
+			result.WriteString(PieceToNotation(movingPiece | WHITE))
+			result.WriteString(sanDisambiguation(b, m))
+		}
+		if m.IsCapture() {
+			result.WriteString("x")
+		}
+		result.WriteString(SquareIndexToNotation(m.To()))
+		if m.IsPromotion() {
+			result.WriteString("=" + PieceToNotation(m.Promotion()|WHITE))
+		}
+	}
+
+	if GivesCheck(b, m) {
+		MakeMove(b, m)
+		if hasLegalMove(b) {
+			result.WriteString("+")
+		} else {
+			result.WriteString("#")
+		}
+		UndoMove(b)
+	}
+
+	return result.String()
+}
+
+// sanDisambiguation returns the file, rank or square of the piece making
+// a move, where needed to tell it apart from identical pieces that could
+// move to the same square.
+func sanDisambiguation(b *Board, m Move) string {
+	piece := b.squares[m.From()]
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, move := range GenerateLegalMoves(b) {
+		if move.To() != m.To() || move.From() == m.From() || b.squares[move.From()] != piece {
+			continue
+		}
+		ambiguous = true
+		if move.From()&0x0F == m.From()&0x0F {
+			sameFile = true
+		}
+		if move.From()&0xF0 == m.From()&0xF0 {
+			sameRank = true
+		}
+	}
+	from := SquareIndexToNotation(m.From())
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
+
+func isSquareNotation(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8'
+}
@@ -0,0 +1,158 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSAN(t *testing.T) {
+	kiwipete := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	tests := []struct {
+		fen  string
+		san  string
+		move string
+	}{
+		{InitialPositionFEN, "e4", "e2e4"},
+		{InitialPositionFEN, "Nf3", "g1f3"},
+		{InitialPositionFEN, "Ng1f3", "g1f3"},
+		{InitialPositionFEN, "Ng1-f3", "g1f3"},
+		{InitialPositionFEN, "Nf3!?", "g1f3"},
+		{kiwipete, "O-O", "e1g1"},
+		{kiwipete, "0-0-0", "e1c1"},
+		{kiwipete, "O-O+", "e1g1"},
+		{kiwipete, "Nxd7", "e5d7"},
+		{kiwipete, "Nd7", "e5d7"},
+		{kiwipete, "dxe6", "d5e6"},
+		{kiwipete, "de6", "d5e6"},
+		{kiwipete, "Qxh3", "f3h3"},
+		{kiwipete, "gxh3", "g2h3"},
+		{kiwipete, "Bxa6", "e2a6"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		// Disambiguation by file, rank and square.
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rad1", "a1d1"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rhd1", "h1d1"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "R1a3", "a1a3"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "R5a3", "a5a3"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qa1b2", "a1b2"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Q3b2", "a3b2"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qcb2", "c1b2"},
+		// Promotions
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=Q+", "a7a8q"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8N", "a7a8n"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "axb8=R+", "a7b8r"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "ab8q", "a7b8q"},
+		{"4k3/8/8/8/8/8/p7/4K3 b - - 0 1", "a1=Q+", "a2a1q"},
+		// e.p.
+		{"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6 0 1", "exd6", "e5d6"},
+		{"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6 0 1", "exd6 e.p.", "e5d6"},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		m, err := ParseSAN(b, test.san)
+		if err != nil {
+			t.Errorf("%s in %s: %s", test.san, test.fen, err)
+			continue
+		}
+		if m.String() != test.move {
+			t.Errorf("%s in %s should be %s, not %s", test.san, test.fen, test.move, m)
+		}
+	}
+
+	invalid := []struct {
+		fen  string
+		san  string
+		want error
+	}{
+		{InitialPositionFEN, "", ErrSANSyntax},
+		{InitialPositionFEN, "e9", ErrSANSyntax},
+		{InitialPositionFEN, "Nf3=Q", ErrSANSyntax},
+		{InitialPositionFEN, "Zf3", ErrSANSyntax},
+		{InitialPositionFEN, "e5", ErrSANIllegal},
+		{InitialPositionFEN, "O-O", ErrSANIllegal},
+		{InitialPositionFEN, "Ke2", ErrSANIllegal},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rd1", ErrSANAmbiguous},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qab2", ErrSANAmbiguous},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qb2", ErrSANAmbiguous},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qa1d1", ErrSANIllegal},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8", ErrSANIllegal},
+	}
+	for _, test := range invalid {
+		b := FromFEN(test.fen)
+		_, err := ParseSAN(b, test.san)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%q in %s should give %q, not %v", test.san, test.fen, test.want, err)
+		}
+	}
+}
+
+func TestFormatSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		san  string
+	}{
+		{InitialPositionFEN, "e2e4", "e4"},
+		{InitialPositionFEN, "g1f3", "Nf3"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1c1", "O-O-O"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e5d7", "Nxd7"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e5f7", "Nxf7"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "d5e6", "dxe6"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w - - 0 1", "h1h8", "Rh8+"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2", "Qa1b2"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a3b2", "Q3b2"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "c1b2", "Qcb2"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7b8r", "axb8=R+"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", "a8=N"},
+		{"r1bqk1nr/ppp2ppp/2n5/1BbpP3/8/5N2/PPPP1PPP/RNBQK2R w KQkq d6 0 1", "e5d6", "exd6"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", "d8h4", "Qh4#"},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		m := mustFindMove(t, b, test.move)
+		fenBefore := ToFEN(b)
+		if san := FormatSAN(b, m); san != test.san {
+			t.Errorf("%s in %s should be %s, not %s", test.move, test.fen, test.san, san)
+		}
+		if ToFEN(b) != fenBefore {
+			t.Errorf("FormatSAN should not change the board")
+		}
+	}
+}
+
+// mustFindMove returns the legal move with the given UCI notation, failing
+// the test if there isn't one.
+func mustFindMove(t *testing.T, b *Board, uci string) Move {
+	t.Helper()
+	for _, m := range GenerateLegalMoves(b) {
+		if m.String() == uci {
+			return m
+		}
+	}
+	t.Fatalf("%s is not legal in %s", uci, ToFEN(b))
+	return NullMove
+}
+
+// Every legal move should survive a round trip through SAN.
+func TestSANRoundTrip(t *testing.T) {
+	fens := []string{
+		InitialPositionFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+		"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1",
+	}
+	for _, fen := range fens {
+		b := FromFEN(fen)
+		for _, m := range GenerateLegalMoves(b) {
+			san := FormatSAN(b, m)
+			parsed, err := ParseSAN(b, san)
+			if err != nil {
+				t.Errorf("%s (%s) in %s: %s", san, m, fen, err)
+			} else if parsed != m {
+				t.Errorf("%s in %s should be %s, not %s", san, fen, m, parsed)
+			}
+		}
+	}
+}
@@ -1,24 +1,69 @@
 package board
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/micaherne/unidexter-go/tt"
+)
 
 type BestMove struct {
 	Move Move
 }
 
-func negamaxInternal(b *Board, depth int, bestMove *BestMove) int {
-	if depth == 0 {
+// maxPly is the deepest a search can go.
+const maxPly = 64
+
+// mateScore is the score for checkmating the opponent at the root. Mates
+// further away score less, so that the quickest is preferred.
+const mateScore = 30000
+
+// MateThreshold is the smallest score, in either direction, which is a mate
+// rather than an evaluation, as the search can't find a mate further from
+// the root than its maximum depth. It is what a tt.Table for the search
+// should be created with.
+const MateThreshold = mateScore - maxPly
+
+// infinity is more than any score a search can return.
+const infinity = mateScore + 1
+
+// searcher holds the state of a search. Each ply has its own move list and
+// picker, so that nothing needs to be allocated as the tree is searched.
+type searcher struct {
+	moveLists [maxPly]MoveList
+	pickers   [maxPly]MovePicker
+	quiets    [maxPly]MoveList // The quiet moves tried so far at each ply.
+	killers   [maxPly][2]Move  // Quiet moves which caused cutoffs at each ply.
+	history   *History         // May be nil to leave quiet moves unordered.
+	// orderHook replaces the move ordering when it isn't nil. Only the
+	// tests set it, to measure what each part of the ordering gains.
+	orderHook func(b *Board, ply int, hashMove Move) moveIterator
+
+	tt      *tt.Table // May be nil.
+	nodes   int
+	qnodes  int // Of the nodes, how many were in the quiescence search.
+	ctx     context.Context
+	limits  SearchLimits
+	start   time.Time
+	hard    time.Duration // Stop part way through a depth after this long.
+	stopped bool
+}
+
+func (s *searcher) negamaxInternal(b *Board, depth int, ply int, bestMove *BestMove) int {
+	if depth == 0 || ply >= maxPly {
 		return Evaluate(b)
 	}
 	max := -10000000
-	moves := GenerateMoves(b)
-	for _, move := range moves {
-		if !LegalMove(b, move) {
-			continue
-		}
+	legalMoves := 0
+	moves := &s.moveLists[ply]
+	moves.Clear()
+	GenerateLegalMovesInto(b, moves)
+	for _, move := range moves.Moves() {
+		legalMoves++
 
 		MakeMove(b, move)
-		score := -negamaxInternal(b, depth-1, bestMove)
+		score := -s.negamaxInternal(b, depth-1, ply+1, bestMove)
 		UndoMove(b)
 
 		if score > max {
@@ -27,21 +72,32 @@ func negamaxInternal(b *Board, depth int, bestMove *BestMove) int {
 
 	}
 
+	if legalMoves == 0 {
+		return noMovesScore(b, ply)
+	}
+
 	return max
 }
 
+// noMovesScore is the score for a position with no legal moves, which is
+// either checkmate or stalemate.
+func noMovesScore(b *Board, ply int) int {
+	if IsCheck(b, ColourToMove(b)) {
+		return -mateScore + ply
+	}
+	return 0
+}
+
 func Negamax(b *Board, depth int) Move {
+	s := &searcher{}
 	bestMove := &BestMove{}
 	max := -10000000
-	moves := GenerateMoves(b)
-	for _, move := range moves {
-		if !LegalMove(b, move) {
-			continue
-		}
-
+	moves := &s.moveLists[0]
+	GenerateLegalMovesInto(b, moves)
+	for _, move := range moves.Moves() {
 		fmt.Printf("info currmove %s\n", move)
 		MakeMove(b, move)
-		score := -negamaxInternal(b, depth-1, bestMove)
+		score := -s.negamaxInternal(b, depth-1, 1, bestMove)
 		UndoMove(b)
 
 		if score > max {
@@ -51,17 +107,13 @@ func Negamax(b *Board, depth int) Move {
 
 	}
 
-	if bestMove.Move.to == 0 && bestMove.Move.from == 0 {
+	if bestMove.Move == NullMove {
 		// TODO: Better last ditch attempt choice.
 		// This just returns the one with the best static evaluation.
 
-		for _, move := range moves {
-			if !LegalMove(b, move) {
-				continue
-			}
-
+		for _, move := range moves.Moves() {
 			MakeMove(b, move)
-			score := -negamaxInternal(b, 0, bestMove)
+			score := -s.negamaxInternal(b, 0, 1, bestMove)
 			UndoMove(b)
 
 			if score > max {
@@ -74,44 +126,244 @@ func Negamax(b *Board, depth int) Move {
 	return bestMove.Move
 }
 
-func negamaxAlphaBetaInternal(b *Board, alpha int, beta int, depth int, bestMove *BestMove) int {
-	if depth == 0 {
-		return Evaluate(b)
+func (s *searcher) negamaxAlphaBetaInternal(b *Board, alpha int, beta int, depth int, ply int, bestMove *BestMove) int {
+	if depth == 0 || ply >= maxPly {
+		return s.quiesce(b, alpha, beta, ply)
 	}
-	moves := GenerateMoves(b)
-	for _, move := range moves {
-		if !LegalMove(b, move) {
-			continue
+	s.nodes++
+	if s.shouldStop() {
+		return 0
+	}
+	if isDraw(b) {
+		return 0
+	}
+
+	hashMove := NullMove
+	if s.tt != nil {
+		if entry, ok := s.tt.Probe(b.zobristKey, ply); ok {
+			hashMove = Move(entry.Move)
+			if entry.Depth >= depth {
+				switch {
+				case entry.Bound == tt.BoundExact:
+					return entry.Score
+				case entry.Bound == tt.BoundLower && entry.Score >= beta:
+					return beta
+				case entry.Bound == tt.BoundUpper && entry.Score <= alpha:
+					return alpha
+				}
+			}
 		}
+	}
+
+	legalMoves := 0
+	var moves moveIterator
+	if s.orderHook != nil {
+		moves = s.orderHook(b, ply, hashMove)
+	} else {
+		picker := &s.pickers[ply]
+		picker.Init(b, hashMove, s.killers[ply], s.history)
+		moves = picker
+	}
+	quiets := &s.quiets[ply]
+	quiets.Clear()
+
+	best := NullMove
+	bound := tt.BoundUpper
+	for move, ok := moves.Next(); ok; move, ok = moves.Next() {
+		legalMoves++
 
 		MakeMove(b, move)
-		score := -negamaxAlphaBetaInternal(b, -beta, -alpha, depth-1, bestMove)
+		score := -s.negamaxAlphaBetaInternal(b, -beta, -alpha, depth-1, ply+1, bestMove)
 		UndoMove(b)
+		if s.stopped {
+			return 0
+		}
 
 		if score >= beta {
+			if isQuiet(move) {
+				s.updateQuiets(b, move, quiets.Moves(), depth, ply)
+			}
+			s.store(b, move, beta, depth, tt.BoundLower, ply)
 			return beta
 		}
+		if isQuiet(move) {
+			quiets.Add(move)
+		}
 		if score > alpha {
 			alpha = score
+			best = move
+			bound = tt.BoundExact
 		}
 
 	}
 
+	if legalMoves == 0 {
+		return noMovesScore(b, ply)
+	}
+
+	s.store(b, best, alpha, depth, bound, ply)
 	return alpha
 }
 
-func NegamaxAlphaBeta(b *Board, depth int) Move {
-	bestMove := &BestMove{}
-	max := -10000000
-	moves := GenerateMoves(b)
-	for _, move := range moves {
-		if !LegalMove(b, move) {
+// moveIterator returns the moves to search in a position one at a time.
+type moveIterator interface {
+	Next() (Move, bool)
+}
+
+// updateQuiets remembers that a quiet move caused a cutoff, as a killer
+// move for the ply and in the history, along with the quiet moves tried
+// before it which didn't.
+func (s *searcher) updateQuiets(b *Board, move Move, tried []Move, depth int, ply int) {
+	if killers := &s.killers[ply]; killers[0] != move {
+		killers[1] = killers[0]
+		killers[0] = move
+	}
+	if s.history != nil {
+		s.history.update(b, move, tried, depth)
+	}
+}
+
+// deltaMargin is how much more than the material it wins a capture might
+// be worth positionally. Captures which can't raise alpha even with it
+// added aren't searched in the quiescence search.
+const deltaMargin = 200
+
+// quiesce searches captures and queen promotions until the position is
+// quiet, so that it isn't evaluated part way through an exchange. The side
+// to move can stand pat on the evaluation instead of capturing, unless it
+// is in check, in which case all the evasions are searched.
+func (s *searcher) quiesce(b *Board, alpha int, beta int, ply int) int {
+	s.nodes++
+	s.qnodes++
+	if s.shouldStop() {
+		return 0
+	}
+	if isDraw(b) {
+		return 0
+	}
+	if ply >= maxPly {
+		score := Evaluate(b)
+		if score == -mateScore {
+			// Evaluate doesn't know how far from the root the mate is.
+			score += ply
+		}
+		return score
+	}
+
+	colour := ColourToMove(b)
+	inCheck := IsCheck(b, colour)
+	moves := &s.moveLists[ply]
+	moves.Clear()
+	standPat := 0
+	if inCheck {
+		GenerateEvasions(b, moves)
+	} else {
+		standPat = Evaluate(b)
+		if standPat >= beta {
+			return beta
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		GenerateCaptures(b, moves)
+	}
+
+	sortMVVLVA(b, moves.Moves())
+
+	pinnedPieces := pinned(b, colour)
+	legalMoves := 0
+	for _, move := range moves.Moves() {
+		if !isLegal(b, move, pinnedPieces) {
 			continue
 		}
+		legalMoves++
+		if !inCheck {
+			// Don't bother with captures which can't get back to alpha
+			// even if the piece is won outright, or which lose material.
+			gain := pieceValues[GetPieceType(b.squares[move.To()])]
+			if move.IsEnPassant() {
+				gain = pieceValues[PAWN]
+			}
+			if move.IsPromotion() {
+				gain += pieceValues[move.Promotion()] - pieceValues[PAWN]
+			}
+			if standPat+gain+deltaMargin <= alpha || !SEEGE(b, move, 0) {
+				continue
+			}
+		}
+
+		MakeMove(b, move)
+		score := -s.quiesce(b, -beta, -alpha, ply+1)
+		UndoMove(b)
+		if s.stopped {
+			return 0
+		}
 
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	if inCheck && legalMoves == 0 {
+		return -mateScore + ply
+	}
+	return alpha
+}
+
+// isDraw reports whether the position is drawn by the fifty move rule,
+// repetition or insufficient material. Repeating a position once is enough
+// to claim a draw if it's good for the opponent.
+func isDraw(b *Board) bool {
+	if b.IsRepetition(2) || insufficientMaterial(b) {
+		return true
+	}
+	// Checkmate on the move that reaches the fifty move limit still counts.
+	return b.halfMove >= 100 && !(IsCheck(b, ColourToMove(b)) && !hasLegalMove(b))
+}
+
+// store saves the result of searching a position in the transposition
+// table, if there is one.
+func (s *searcher) store(b *Board, move Move, score int, depth int, bound tt.Bound, ply int) {
+	if s.tt != nil {
+		s.tt.Store(b.zobristKey, uint16(move), score, depth, bound, ply)
+	}
+}
+
+// shouldStop reports whether the search has been stopped, or has used up
+// its nodes or time. The clock and context are only checked every so often
+// as they are slower.
+func (s *searcher) shouldStop() bool {
+	if s.stopped {
+		return true
+	}
+	if s.limits.Nodes > 0 && s.nodes > s.limits.Nodes {
+		s.stopped = true
+	} else if s.nodes&1023 == 0 {
+		if s.ctx != nil && s.ctx.Err() != nil {
+			s.stopped = true
+		}
+		if s.hard > 0 && time.Since(s.start) >= s.hard {
+			s.stopped = true
+		}
+	}
+	return s.stopped
+}
+
+func NegamaxAlphaBeta(b *Board, depth int) Move {
+	s := &searcher{history: &History{}}
+	bestMove := &BestMove{}
+	// Every score is more than -infinity, so there is a best move as long as
+	// there are any legal moves.
+	max := -infinity
+	moves := &s.moveLists[0]
+	GenerateLegalMovesInto(b, moves)
+	for _, move := range moves.Moves() {
 		fmt.Printf("info currmove %s\n", move)
 		MakeMove(b, move)
-		score := -negamaxAlphaBetaInternal(b, -10000, 10000, depth-1, bestMove)
+		score := -s.negamaxAlphaBetaInternal(b, -infinity, infinity, depth-1, 1, bestMove)
 		UndoMove(b)
 
 		if score > max {
@@ -120,26 +372,142 @@ func NegamaxAlphaBeta(b *Board, depth int) Move {
 		}
 
 	}
+	return bestMove.Move
+}
 
-	if bestMove.Move.to == 0 && bestMove.Move.from == 0 {
-		// TODO: Better last ditch attempt choice.
-		// This just returns the one with the best static evaluation.
+// SearchInfo describes the result of searching to one depth.
+type SearchInfo struct {
+	Depth int
+	Move  Move
+	Score int // From the point of view of the side to move.
+	Mate  int // Moves until mate, negative if the side to move is being mated, or 0.
+	Nodes int
+	// Of the Nodes, how many were in the quiescence search.
+	QNodes int
+	Time   time.Duration
+	// How full the transposition table is in permille, or 0 if there isn't
+	// one.
+	Hashfull int
+}
 
-		for _, move := range moves {
-			if !LegalMove(b, move) {
-				continue
-			}
+// Search searches the position to greater and greater depths until it
+// reaches the limits or ctx is cancelled, and returns the best move from
+// the deepest search it finished. If even the first depth doesn't finish it
+// returns the first legal move, and NullMove only if there are none. report
+// is called after each depth if it isn't nil.
+//
+// The transposition table and history may be nil, but keeping them between
+// moves lets each search use what the last one found. The history is aged
+// at the start of each search so that it counts for less than what the
+// search finds itself.
+//
+// The board is changed while the search runs, so search a clone if
+// anything else may be using it.
+func Search(ctx context.Context, b *Board, table *tt.Table, history *History, limits SearchLimits, report func(SearchInfo)) Move {
+	if history == nil {
+		history = &History{}
+	} else {
+		history.age()
+	}
+	s := &searcher{tt: table, history: history, ctx: ctx, limits: limits}
+	return s.iterate(b, report)
+}
 
-			MakeMove(b, move)
-			score := -negamaxInternal(b, 0, bestMove)
-			UndoMove(b)
+// iterate does the iterative deepening for Search.
+func (s *searcher) iterate(b *Board, report func(SearchInfo)) Move {
+	s.start = time.Now()
+	table, limits := s.tt, s.limits
+	if table != nil {
+		table.NewSearch()
+	}
+	soft, hard := limits.timeBudget(ColourToMove(b))
+	s.hard = hard
 
-			if score > max {
-				max = score
-				bestMove.Move = move
-			}
+	moves := &s.moveLists[0]
+	GenerateLegalMovesInto(b, moves)
+	if moves.Len() == 0 {
+		return NullMove
+	}
+	best := moves.At(0)
 
+	maxDepth := maxPly - 1
+	if limits.Depth > 0 && limits.Depth < maxDepth {
+		maxDepth = limits.Depth
+	}
+	if limits.Mate > 0 && 2*limits.Mate-1 < maxDepth {
+		maxDepth = 2*limits.Mate - 1
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		move, score, ok := s.searchRoot(b, depth, best)
+		if !ok {
+			break
+		}
+		best = move
+		info := SearchInfo{
+			Depth:  depth,
+			Move:   move,
+			Score:  score,
+			Mate:   mateIn(score),
+			Nodes:  s.nodes,
+			QNodes: s.qnodes,
+			Time:   time.Since(s.start),
+		}
+		if table != nil {
+			info.Hashfull = table.Hashfull()
+		}
+		if report != nil {
+			report(info)
+		}
+		if limits.Mate > 0 && info.Mate > 0 && info.Mate <= limits.Mate {
+			break
+		}
+		// There's no point using time when there's only one move.
+		if soft > 0 && (info.Time >= soft || moves.Len() == 1) {
+			break
 		}
 	}
-	return bestMove.Move
+	return best
+}
+
+// searchRoot searches the root moves to the given depth, starting with the
+// best move from the last depth, and returns the best move and its score.
+// It returns false if the search was stopped before it finished.
+func (s *searcher) searchRoot(b *Board, depth int, previousBest Move) (Move, int, bool) {
+	moves := &s.moveLists[0]
+	for i, move := range moves.Moves() {
+		if move == previousBest {
+			moves.moves[0], moves.moves[i] = move, moves.moves[0]
+			break
+		}
+	}
+
+	var unused BestMove
+	bestMove := NullMove
+	alpha := -infinity
+	for _, move := range moves.Moves() {
+		MakeMove(b, move)
+		score := -s.negamaxAlphaBetaInternal(b, -infinity, -alpha, depth-1, 1, &unused)
+		UndoMove(b)
+		if s.stopped {
+			return NullMove, 0, false
+		}
+		if score > alpha {
+			alpha = score
+			bestMove = move
+		}
+	}
+	return bestMove, alpha, true
+}
+
+// mateIn returns the number of moves until mate for a score, negative if the
+// side to move is being mated, or 0 if the score isn't a mate.
+func mateIn(score int) int {
+	switch {
+	case score >= MateThreshold:
+		return (mateScore - score + 1) / 2
+	case score <= -MateThreshold:
+		return -(mateScore + score) / 2
+	}
+	return 0
 }
@@ -0,0 +1,320 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micaherne/unidexter-go/tt"
+)
+
+func TestSearchDoesNotAllocate(t *testing.T) {
+	if validateMoves {
+		t.Skip("Validating moves allocates")
+	}
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	s := &searcher{}
+	bestMove := &BestMove{}
+	search := func() { s.negamaxAlphaBetaInternal(b, -10000, 10000, 3, 0, bestMove) }
+	if allocs := testing.AllocsPerRun(5, search); allocs != 0 {
+		t.Errorf("Search should not allocate, but made %v allocations", allocs)
+	}
+}
+
+// Searches on clones of a board can run at the same time. Run with -race to
+// check that they don't share anything they change.
+func TestSearchOnClonesConcurrently(t *testing.T) {
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	MakeMoveFromNotation(b, "e1g1")
+	fen := ToFEN(b)
+	search := func(b *Board) int {
+		return (&searcher{}).negamaxAlphaBetaInternal(b, -10000, 10000, 2, 0, &BestMove{})
+	}
+	want := search(b.Clone())
+
+	results := make(chan int)
+	for i := 0; i < 4; i++ {
+		go func(c *Board) {
+			results <- search(c)
+		}(b.Clone())
+	}
+	for i := 0; i < 4; i++ {
+		if got := <-results; got != want {
+			t.Errorf("Search on a clone should score %d, not %d", want, got)
+		}
+	}
+	if ToFEN(b) != fen {
+		t.Errorf("Original should still be %s, not %s", fen, ToFEN(b))
+	}
+}
+
+func TestParseSearchLimits(t *testing.T) {
+	args := []string{"wtime", "60000", "btime", "-20", "winc", "1000", "binc", "0", "movestogo", "40",
+		"ponder", "searchmoves", "e2e4", "depth", "6", "nodes", "5000", "mate", "3", "movetime", "250", "infinite"}
+	got, err := ParseSearchLimits(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SearchLimits{
+		WhiteTime: time.Minute,
+		BlackTime: -20 * time.Millisecond,
+		WhiteInc:  time.Second,
+		MovesToGo: 40,
+		MoveTime:  250 * time.Millisecond,
+		Depth:     6,
+		Nodes:     5000,
+		Mate:      3,
+		Infinite:  true,
+	}
+	if got != want {
+		t.Errorf("Limits should be %+v, not %+v", want, got)
+	}
+
+	for _, args := range [][]string{{"wtime"}, {"depth", "x"}, {"nodes", "-1"}, {"movetime", "1.5"}} {
+		if _, err := ParseSearchLimits(args); !errors.Is(err, ErrSearchLimit) {
+			t.Errorf("%v should give %v, not %v", args, ErrSearchLimit, err)
+		}
+	}
+}
+
+func TestTimeBudget(t *testing.T) {
+	tests := []struct {
+		limits     SearchLimits
+		colour     int
+		soft, hard time.Duration
+	}{
+		{SearchLimits{}, WHITE, 0, 0},
+		{SearchLimits{Depth: 5}, BLACK, 0, 0},
+		{SearchLimits{MoveTime: time.Second}, WHITE, time.Second - moveOverhead, time.Second - moveOverhead},
+		// A thirtieth of the time left, and four times that to finish a depth.
+		{SearchLimits{WhiteTime: 30*time.Second + moveOverhead, BlackTime: time.Second}, WHITE, time.Second, 4 * time.Second},
+		{SearchLimits{WhiteTime: time.Second, BlackTime: 30*time.Second + moveOverhead}, BLACK, time.Second, 4 * time.Second},
+		// Most of the increment can be used as well.
+		{SearchLimits{BlackTime: 30*time.Second + moveOverhead, BlackInc: 2 * time.Second}, BLACK, 2500 * time.Millisecond, 10 * time.Second},
+		// But never more than half the time left.
+		{SearchLimits{WhiteTime: 2*time.Second + moveOverhead, MovesToGo: 2}, WHITE, time.Second, time.Second},
+		// Unless it's the last move before the time control.
+		{SearchLimits{WhiteTime: 2*time.Second + moveOverhead, MovesToGo: 1}, WHITE, 2 * time.Second, 2 * time.Second},
+		// Even with no time left there's something to search with.
+		{SearchLimits{WhiteTime: -time.Second}, WHITE, time.Millisecond / 30, time.Millisecond / 30 * 4},
+	}
+	for _, test := range tests {
+		soft, hard := test.limits.timeBudget(test.colour)
+		if soft != test.soft || hard != test.hard {
+			t.Errorf("%+v should give soft %v and hard %v, not %v and %v", test.limits, test.soft, test.hard, soft, hard)
+		}
+	}
+}
+
+func TestSearchDepth(t *testing.T) {
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	fen := ToFEN(b)
+	depths := []int{}
+	move := Search(context.Background(), b, nil, nil, SearchLimits{Depth: 3}, func(info SearchInfo) {
+		depths = append(depths, info.Depth)
+	})
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(depths, want) {
+		t.Errorf("Should have searched to depths %v, not %v", want, depths)
+	}
+	if !IsLegal(b, move) {
+		t.Errorf("%s should be a legal move", move)
+	}
+	if ToFEN(b) != fen {
+		t.Errorf("Board should be unchanged after the search, not %s", ToFEN(b))
+	}
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	tests := []struct {
+		fen  string
+		mate int
+		move string
+	}{
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 1, "a1a8"},
+		{"k7/8/2K5/8/8/8/8/7R w - - 0 1", 2, ""},
+		{"k7/8/1K6/8/8/8/8/7R b - - 0 1", -1, "a8b8"},
+	}
+	for _, table := range []*tt.Table{nil, tt.New(1, MateThreshold)} {
+		for _, test := range tests {
+			b := FromFEN(test.fen)
+			var last SearchInfo
+			move := Search(context.Background(), b, table, nil, SearchLimits{Depth: 4}, func(info SearchInfo) {
+				last = info
+			})
+			if last.Mate != test.mate {
+				t.Errorf("%s should be mate in %d, not %d", test.fen, test.mate, last.Mate)
+			}
+			if test.move != "" && move.String() != test.move {
+				t.Errorf("Best move in %s should be %s, not %s", test.fen, test.move, move)
+			}
+		}
+	}
+
+	// A mate limit stops the search as soon as a mate that quick is found.
+	b := FromFEN("k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	var last SearchInfo
+	Search(context.Background(), b, nil, nil, SearchLimits{Mate: 2}, func(info SearchInfo) {
+		last = info
+	})
+	if last.Depth != 3 || last.Mate != 2 {
+		t.Errorf("Mate 2 should stop at depth 3 with mate in 2, not depth %d and mate in %d", last.Depth, last.Mate)
+	}
+}
+
+func TestSearchStops(t *testing.T) {
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	fen := ToFEN(b)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	move := Search(ctx, b, nil, nil, SearchLimits{Infinite: true}, nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Search should stop soon after being cancelled, but took %v", elapsed)
+	}
+	if !IsLegal(b, move) {
+		t.Errorf("%s should be a legal move", move)
+	}
+
+	start = time.Now()
+	move = Search(context.Background(), b, nil, nil, SearchLimits{MoveTime: 200 * time.Millisecond}, nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Search with movetime 200 took %v", elapsed)
+	}
+	if !IsLegal(b, move) {
+		t.Errorf("%s should be a legal move", move)
+	}
+
+	var last SearchInfo
+	move = Search(context.Background(), b, nil, nil, SearchLimits{Nodes: 5000}, func(info SearchInfo) {
+		last = info
+	})
+	if last.Nodes > 5000 {
+		t.Errorf("Search should stop after 5000 nodes, not %d", last.Nodes)
+	}
+	if move != last.Move {
+		t.Errorf("Search should return %s from the last depth finished, not %s", last.Move, move)
+	}
+	if ToFEN(b) != fen {
+		t.Errorf("Board should be unchanged after the search, not %s", ToFEN(b))
+	}
+}
+
+func TestSearchWithTranspositionTable(t *testing.T) {
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	limits := SearchLimits{Depth: 4}
+	search := func(table *tt.Table) SearchInfo {
+		var last SearchInfo
+		move := Search(context.Background(), b, table, nil, limits, func(info SearchInfo) {
+			last = info
+		})
+		if move != last.Move || !IsLegal(b, move) {
+			t.Errorf("Search should return the legal move %s from the last depth, not %s", last.Move, move)
+		}
+		return last
+	}
+
+	without := search(nil)
+	table := tt.New(1, MateThreshold)
+	with := search(table)
+	if with.Nodes >= without.Nodes {
+		t.Errorf("Search should visit fewer nodes with a transposition table, but visited %d rather than %d", with.Nodes, without.Nodes)
+	}
+	if with.Hashfull == 0 {
+		t.Error("Search should report how full the table is")
+	}
+
+	// Searching again finds the results of the last search.
+	if again := search(table); again.Nodes >= with.Nodes/2 {
+		t.Errorf("Searching again should use the table, but visited %d nodes rather than %d", again.Nodes, with.Nodes)
+	}
+}
+
+func TestSearchWithTableDoesNotAllocate(t *testing.T) {
+	if validateMoves {
+		t.Skip("Validating moves allocates")
+	}
+	b := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	s := &searcher{tt: tt.New(1, MateThreshold)}
+	bestMove := &BestMove{}
+	search := func() { s.negamaxAlphaBetaInternal(b, -infinity, infinity, 3, 0, bestMove) }
+	if allocs := testing.AllocsPerRun(5, search); allocs != 0 {
+		t.Errorf("Search should not allocate, but made %v allocations", allocs)
+	}
+}
+
+func TestQuiesce(t *testing.T) {
+	// Quiet, so the evaluation stands.
+	b := FromFEN(InitialPositionFEN)
+	s := &searcher{}
+	if got, want := s.quiesce(b, -infinity, infinity, 0), Evaluate(b); got != want {
+		t.Errorf("Quiet position should score its evaluation %d, not %d", want, got)
+	}
+
+	// The queen can be taken by a pawn.
+	b = FromFEN("4k3/8/8/3q4/4P3/8/8/4K3 w - - 0 1")
+	if got, least := s.quiesce(b, -infinity, infinity, 0), Evaluate(b)+700; got < least {
+		t.Errorf("Capturing the queen should score at least %d, not %d", least, got)
+	}
+
+	// Taking the pawn loses the queen, so standing pat is better.
+	b = FromFEN("4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1")
+	if got, want := s.quiesce(b, -infinity, infinity, 0), Evaluate(b); got != want {
+		t.Errorf("Defended pawn should not be taken, so score %d, not %d", want, got)
+	}
+
+	// In check, standing pat isn't allowed, so mate is found.
+	b = FromFEN("rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	if got := s.quiesce(b, -infinity, infinity, 2); got != -mateScore+2 {
+		t.Errorf("Checkmate should score %d, not %d", -mateScore+2, got)
+	}
+	if s.qnodes == 0 || s.qnodes != s.nodes {
+		t.Errorf("Quiescence nodes should be counted, but got %d of %d nodes", s.qnodes, s.nodes)
+	}
+}
+
+func TestSearchDoesNotHangPieces(t *testing.T) {
+	b := FromFEN("4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1")
+	var last SearchInfo
+	move := Search(context.Background(), b, nil, nil, SearchLimits{Depth: 2}, func(info SearchInfo) {
+		last = info
+	})
+	if move.String() == "d1d5" {
+		t.Errorf("Search should not give the queen for a pawn with %s", move)
+	}
+	if last.QNodes == 0 || last.QNodes >= last.Nodes {
+		t.Errorf("Search should report quiescence nodes as part of the nodes, not %d of %d", last.QNodes, last.Nodes)
+	}
+}
+
+func TestNegamaxAlphaBetaFindsMate(t *testing.T) {
+	b := FromFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	if move := NegamaxAlphaBeta(b, 2); move.String() != "a1a8" {
+		t.Errorf("Should find mate with a1a8, not %s", move)
+	}
+	// Every move loses, but one must still be chosen.
+	b = FromFEN("k7/8/1K6/8/8/8/8/7R b - - 0 1")
+	if move := NegamaxAlphaBeta(b, 3); move.String() != "a8b8" {
+		t.Errorf("Should play the only move a8b8, not %s", move)
+	}
+}
+
+func TestMateBeatsFiftyMoveRule(t *testing.T) {
+	// Rook mate on the 100th half move.
+	b := FromFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 99 80")
+	var last SearchInfo
+	move := Search(context.Background(), b, nil, nil, SearchLimits{Depth: 2}, func(info SearchInfo) {
+		last = info
+	})
+	if move.String() != "a1a8" || last.Mate != 1 {
+		t.Errorf("Should find mate in 1 with a1a8, not %s with mate in %d", move, last.Mate)
+	}
+
+	// Other positions are drawn.
+	b = FromFEN("6k1/5ppp/8/8/8/8/8/R5K1 b - - 100 80")
+	if !isDraw(b) {
+		t.Error("Position should be drawn by the fifty move rule")
+	}
+}
@@ -0,0 +1,125 @@
+package board
+
+// SEE returns the static exchange evaluation of a move: the material the
+// side to move wins or loses by making it, if both sides then keep capturing
+// on the same square with their least valuable piece for as long as it pays.
+// Pieces which attack the square through others, e.g. a rook behind a rook,
+// join in once the way is clear, and pawns reaching the last rank count as
+// promoting to a queen.
+func SEE(b *Board, m Move) int {
+	if m.IsCastling() {
+		return 0
+	}
+	from, to := m.From(), m.To()
+	colour := GetColour(b.squares[from])
+	occupied := b.occupied() &^ squareBB(from)
+
+	// gain[i] is the material won by the side making the ith capture, if the
+	// exchange stops after it.
+	var gain [32]int
+	if m.IsEnPassant() {
+		gain[0] = pieceValues[PAWN]
+		occupied &^= squareBB(from&0xF0 | to&0x0F)
+	} else {
+		gain[0] = pieceValues[GetPieceType(b.squares[to])]
+	}
+	onSquare := GetPieceType(b.squares[from])
+	if m.IsPromotion() {
+		onSquare = m.Promotion()
+		gain[0] += pieceValues[onSquare] - pieceValues[PAWN]
+	}
+
+	attackers := attackersTo(b, to, WHITE, occupied) | attackersTo(b, to, BLACK, occupied)
+	depth := 0
+	for depth < len(gain)-1 {
+		colour = GetOpponentColour(colour)
+		square, piece := leastValuableAttacker(b, attackers&b.colourBitboards[colour>>3])
+		if piece == EMPTY {
+			break
+		}
+		// The king can only recapture if the square is no longer defended,
+		// including by a slider behind the king which it would uncover.
+		if piece == KING {
+			remaining := occupied &^ squareBB(square)
+			defenders := (attackers | xrayAttackers(b, to, remaining)) & remaining
+			if defenders&b.colourBitboards[GetOpponentColour(colour)>>3] != 0 {
+				break
+			}
+		}
+
+		depth++
+		gain[depth] = pieceValues[onSquare] - gain[depth-1]
+		onSquare = piece
+		if piece == PAWN && squareBB(to)&(rank1|rank8) != 0 {
+			onSquare = QUEEN
+			gain[depth] += pieceValues[QUEEN] - pieceValues[PAWN]
+		}
+
+		occupied &^= squareBB(square)
+		attackers |= xrayAttackers(b, to, occupied)
+		attackers &= occupied
+	}
+
+	// Work back from the end of the exchange, with each side stopping
+	// early if carrying on would lose more.
+	for ; depth > 0; depth-- {
+		if gain[depth] > -gain[depth-1] {
+			gain[depth-1] = -gain[depth]
+		}
+	}
+	return gain[0]
+}
+
+// SEEGE reports whether the static exchange evaluation of a move is at
+// least the threshold given. It's quicker than calling SEE when the first
+// capture is enough to decide it, as it often is.
+func SEEGE(b *Board, m Move, threshold int) bool {
+	if m.IsCastling() {
+		return threshold <= 0
+	}
+	from, to := m.From(), m.To()
+	best := pieceValues[GetPieceType(b.squares[to])]
+	moving := GetPieceType(b.squares[from])
+	if m.IsEnPassant() {
+		best = pieceValues[PAWN]
+	}
+	if m.IsPromotion() {
+		moving = m.Promotion()
+		best += pieceValues[moving] - pieceValues[PAWN]
+	}
+
+	// The most that can be won is the first capture, and at worst the
+	// moving piece is lost for it, unless a pawn can promote as it
+	// recaptures.
+	if best < threshold {
+		return false
+	}
+	if best-pieceValues[moving] >= threshold && squareBB(to)&(rank1|rank8) == 0 {
+		return true
+	}
+	return SEE(b, m) >= threshold
+}
+
+// leastValuableAttacker returns the square and type of the least valuable
+// piece among the attackers given.
+func leastValuableAttacker(b *Board, attackers uint64) (int, int) {
+	if attackers == 0 {
+		return -1, EMPTY
+	}
+	for pieceType := PAWN; pieceType <= KING; pieceType++ {
+		pieces := attackers & (b.pieceBitboards[WHITE|pieceType] | b.pieceBitboards[BLACK|pieceType])
+		if pieces != 0 {
+			return bitboardSquare(pieces), pieceType
+		}
+	}
+	return -1, EMPTY
+}
+
+// xrayAttackers returns the sliders of either colour attacking a square
+// with only the given squares occupied.
+func xrayAttackers(b *Board, square int, occupied uint64) uint64 {
+	queens := b.pieceBitboards[WHITE|QUEEN] | b.pieceBitboards[BLACK|QUEEN]
+	bishops := b.pieceBitboards[WHITE|BISHOP] | b.pieceBitboards[BLACK|BISHOP] | queens
+	rooks := b.pieceBitboards[WHITE|ROOK] | b.pieceBitboards[BLACK|ROOK] | queens
+	return bishopAttacks(square, occupied)&bishops | rookAttacks(square, occupied)&rooks
+}
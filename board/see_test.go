@@ -0,0 +1,77 @@
+package board
+
+import "testing"
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		see  int
+	}{
+		// Undefended pawn.
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", 100},
+		// Long exchange with x-rays on both sides: NxP NxN RxN BxR QxB.
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", -200},
+		// Pawn takes a defended knight.
+		{"4k3/8/3p4/4n3/3P4/8/8/4K3 w - - 0 1", "d4e5", 200},
+		// Queen takes a defended pawn.
+		{"4k3/8/3p4/4p3/8/8/1Q6/4K3 w - - 0 1", "b2e5", -775},
+		// A rook behind the first one makes the capture safe.
+		{"4r1k1/8/8/4p3/8/8/4R3/4R1K1 w - - 0 1", "e2e5", 100},
+		{"4r1k1/8/8/4p3/8/8/4R3/6K1 w - - 0 1", "e2e5", -400},
+		// En passant.
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		{"4k3/2p5/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 0},
+		// Promotion, where the king can recapture unless the square is defended.
+		{"3rk3/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8q", 400},
+		{"3rk3/4P3/8/8/8/8/8/3RK3 w - - 0 1", "e7d8q", 1275},
+		// A pawn recapturing on the last rank promotes.
+		{"4k3/8/8/8/8/8/1p2N3/2b1K3 w - - 0 1", "e2c1", -765},
+		// Quiet moves, which may leave a piece where it can be taken.
+		{"4k3/8/8/8/8/2p5/8/1N2K3 w - - 0 1", "b1d2", -200},
+		{"4k3/8/8/8/2p5/8/8/1N2K3 w - - 0 1", "b1d2", 0},
+		{"4k3/8/8/8/8/8/2p5/3NK3 w - - 0 1", "d1b2", 0},
+		{"4k3/8/8/8/8/2p5/8/3NK3 w - - 0 1", "d1b2", -300},
+		// Castling
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", 0},
+	}
+	for _, test := range tests {
+		b := FromFEN(test.fen)
+		m := mustFindMove(t, b, test.move)
+		if see := SEE(b, m); see != test.see {
+			t.Errorf("SEE of %s in %s should be %d, not %d", test.move, test.fen, test.see, see)
+		}
+		if !SEEGE(b, m, test.see) || SEEGE(b, m, test.see+1) {
+			t.Errorf("SEEGE of %s in %s should be true only up to %d", test.move, test.fen, test.see)
+		}
+	}
+
+	// The king can't recapture when a slider behind it would then attack
+	// the square. That can only happen when the king is in check, so the
+	// first capture isn't legal, but SEE doesn't check that.
+	b := FromFEN("k3r3/8/8/8/8/6b1/4K3/R3n3 w - - 0 1")
+	m := NewMove(b, NotationToSquareIndex("a1"), NotationToSquareIndex("e1"), EMPTY)
+	if see := SEE(b, m); see != -200 {
+		t.Errorf("SEE of a1e1 should be -200 without the king recapturing, not %d", see)
+	}
+}
+
+// SEEGE should agree with SEE for every legal capture in the perft suite.
+func TestSEEGEMatchesSEE(t *testing.T) {
+	forEachPerftPosition(t, func(b *Board) {
+		var list MoveList
+		GenerateCaptures(b, &list)
+		pinnedPieces := pinned(b, ColourToMove(b))
+		for _, m := range list.Moves() {
+			if IsCheck(b, ColourToMove(b)) || !isLegal(b, m, pinnedPieces) {
+				continue
+			}
+			see := SEE(b, m)
+			for _, threshold := range []int{-500, -100, 0, 1, 100, 500} {
+				if SEEGE(b, m, threshold) != (see >= threshold) {
+					t.Fatalf("SEEGE(%d) of %s in %s disagrees with SEE of %d", threshold, m, ToFEN(b), see)
+				}
+			}
+		}
+	})
+}
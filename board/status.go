@@ -0,0 +1,92 @@
+package board
+
+// GameStatus describes whether the game is over in a position, and why.
+type GameStatus int
+
+const (
+	Ongoing GameStatus = iota
+	Checkmate
+	Stalemate
+	FiftyMoveRule
+	ThreefoldRepetition
+	FivefoldRepetition
+	SeventyFiveMoveRule
+	InsufficientMaterial
+)
+
+var gameStatusNames = []string{
+	"ongoing",
+	"checkmate",
+	"stalemate",
+	"fifty-move rule",
+	"threefold repetition",
+	"fivefold repetition",
+	"seventy-five-move rule",
+	"insufficient material",
+}
+
+func (s GameStatus) String() string {
+	if s < 0 || int(s) >= len(gameStatusNames) {
+		return "unknown"
+	}
+	return gameStatusNames[s]
+}
+
+// IsDraw reports whether the status is a draw. FiftyMoveRule and
+// ThreefoldRepetition are draws that a player can claim, the others end
+// the game immediately.
+func (s GameStatus) IsDraw() bool {
+	return s != Ongoing && s != Checkmate
+}
+
+// Status works out whether the game has ended in the current position.
+// Checkmate and stalemate take priority over the draw rules, as the game
+// is over as soon as they occur.
+func (b *Board) Status() GameStatus {
+	if !hasLegalMove(b) {
+		if IsCheck(b, ColourToMove(b)) {
+			return Checkmate
+		}
+		return Stalemate
+	}
+	if b.IsRepetition(5) {
+		return FivefoldRepetition
+	}
+	if b.halfMove >= 150 {
+		return SeventyFiveMoveRule
+	}
+	if insufficientMaterial(b) {
+		return InsufficientMaterial
+	}
+	if b.IsRepetition(3) {
+		return ThreefoldRepetition
+	}
+	if b.halfMove >= 100 {
+		return FiftyMoveRule
+	}
+	return Ongoing
+}
+
+// hasLegalMove reports whether the side to move has any legal move.
+func hasLegalMove(b *Board) bool {
+	var list MoveList
+	GenerateLegalMovesInto(b, &list)
+	return list.Len() > 0
+}
+
+// insufficientMaterial reports whether neither side can possibly mate, i.e.
+// there are no pawns, rooks or queens, and either a single minor piece or only
+// bishops which are all on the same colour square.
+func insufficientMaterial(b *Board) bool {
+	for _, piece := range []int{PAWN, ROOK, QUEEN} {
+		if b.pieceBitboards[WHITE|piece]|b.pieceBitboards[BLACK|piece] != 0 {
+			return false
+		}
+	}
+	knights := b.pieceBitboards[WHITE|KNIGHT] | b.pieceBitboards[BLACK|KNIGHT]
+	bishops := b.pieceBitboards[WHITE|BISHOP] | b.pieceBitboards[BLACK|BISHOP]
+	if Bitboard(knights|bishops).Count() <= 1 {
+		return true
+	}
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
@@ -0,0 +1,55 @@
+package board
+
+import "testing"
+
+func TestStatus(t *testing.T) {
+	correct := map[string]GameStatus{
+		InitialPositionFEN: Ongoing,
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3": Checkmate, // fool's mate
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1":                                Stalemate,
+		"7k/8/6K1/8/8/8/8/8 w - - 0 1":                                  InsufficientMaterial,
+		"7k/8/6K1/8/8/8/8/5N2 w - - 0 1":                                InsufficientMaterial,
+		"7k/8/6K1/8/8/8/8/2B2B2 w - - 0 1":                              Ongoing,
+		"2b4k/8/6K1/8/8/8/8/5B2 w - - 0 1":                              InsufficientMaterial,
+		"1b5k/8/6K1/8/8/8/8/5B2 w - - 0 1":                              Ongoing,
+		"7k/8/6K1/8/8/8/8/4NN2 w - - 0 1":                               Ongoing,
+		"7k/8/6K1/8/8/8/8/7R w - - 99 80":                               Ongoing,
+		"7k/8/6K1/8/8/8/8/7R b - - 100 80":                              FiftyMoveRule,
+		"7k/8/6K1/8/8/8/8/7R b - - 150 80":                              SeventyFiveMoveRule,
+		"R6k/8/6K1/8/8/8/8/8 b - - 100 80":                              Checkmate,
+		"R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1":                             Checkmate,
+		// The king in check with no free squares can still capture the checker.
+		"6Rk/6pp/8/8/8/8/8/6K1 b - - 0 1":  Ongoing,
+		"5rRk/6pp/8/8/8/8/8/6K1 b - - 0 1": Ongoing,
+	}
+	for fen, status := range correct {
+		b := FromFEN(fen)
+		if s := b.Status(); s != status {
+			t.Errorf("Status of %s should be %s, not %s", fen, status, s)
+		}
+	}
+}
+
+func TestStatusRepetition(t *testing.T) {
+	b := FromFEN(InitialPositionFEN)
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	for i, want := range []GameStatus{Ongoing, ThreefoldRepetition, ThreefoldRepetition, FivefoldRepetition} {
+		for _, move := range shuffle {
+			MakeMoveFromNotation(b, move)
+		}
+		if s := b.Status(); s != want {
+			t.Errorf("Status after %d repetitions should be %s, not %s", i+1, want, s)
+		}
+	}
+}
+
+func TestGameStatusIsDraw(t *testing.T) {
+	if Ongoing.IsDraw() || Checkmate.IsDraw() {
+		t.Error("Ongoing and Checkmate are not draws")
+	}
+	for _, s := range []GameStatus{Stalemate, FiftyMoveRule, ThreefoldRepetition, FivefoldRepetition, SeventyFiveMoveRule, InsufficientMaterial} {
+		if !s.IsDraw() {
+			t.Errorf("%s should be a draw", s)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors reported by Validate, wrapped in a *ValidationError. Problems which
+// a FEN string can also have, such as castling rights without a rook, are
+// reported with the same errors as ParseFEN uses.
+var (
+	ErrKingSquare = errors.New("king square does not match the board")
+	ErrOffBoard   = errors.New("piece on a square off the board")
+	ErrBitboards  = errors.New("bitboards do not match the board")
+	ErrHash       = errors.New("hash does not match the position")
+	ErrPieceCount = errors.New("too many pieces")
+)
+
+// ValidationError describes what is wrong with a board.
+type ValidationError struct {
+	Err    error
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("invalid board: %s", e.Err)
+	}
+	return fmt.Sprintf("invalid board: %s: %s", e.Err, e.Detail)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that the board is internally consistent, e.g. that the
+// bitboards, king squares and hash agree with the pieces on the squares,
+// and that the castling rights, en passant square and number of pieces make
+// sense. It is for debugging, as a board that is only changed by MakeMove
+// and UndoMove should always pass. Unlike ParseFEN it doesn't mind the side
+// not to move being in check, as LegalMove makes moves that leave it so.
+//
+// Building with the boarddebug tag validates the board after every move
+// made or undone, and panics as soon as something goes wrong.
+func (b *Board) Validate() error {
+	fail := func(err error, detail string) error {
+		return &ValidationError{Err: err, Detail: detail}
+	}
+
+	var pieceBitboards [15]uint64
+	var colourBitboards [2]uint64
+	for square, piece := range b.squares {
+		if piece == EMPTY {
+			continue
+		}
+		if !LegalSquareIndex(square) {
+			return fail(ErrOffBoard, fmt.Sprintf("%#x", square))
+		}
+		pieceBitboards[piece] |= squareBB(square)
+		colourBitboards[GetColour(piece)>>3] |= squareBB(square)
+	}
+	if pieceBitboards != b.pieceBitboards || colourBitboards != b.colourBitboards {
+		return fail(ErrBitboards, "")
+	}
+
+	if err := checkPieces(b); err != nil {
+		return fail(err.Err, err.Detail)
+	}
+	for _, king := range []struct {
+		piece  int
+		square int
+	}{{WHITE | KING, b.whiteKing}, {BLACK | KING, b.blackKing}} {
+		if !LegalSquareIndex(king.square) || b.squares[king.square] != king.piece {
+			return fail(ErrKingSquare, PieceToNotation(king.piece))
+		}
+	}
+	if err := checkRights(b); err != nil {
+		return fail(err.Err, err.Detail)
+	}
+	if err := checkPieceCounts(b); err != nil {
+		return err
+	}
+
+	if b.zobristKey != b.FullZobristHash() {
+		return fail(ErrHash, "")
+	}
+	return nil
+}
+
+// checkPieceCounts checks that neither side has more pieces than it could
+// have in a game, counting any beyond the starting set as promoted pawns.
+func checkPieceCounts(b *Board) error {
+	starting := [...]int{PAWN: 8, KNIGHT: 2, BISHOP: 2, ROOK: 2, QUEEN: 1, KING: 1}
+	for colour, name := range map[int]string{WHITE: "white", BLACK: "black"} {
+		promoted := 0
+		for pieceType := KNIGHT; pieceType <= QUEEN; pieceType++ {
+			if extra := Bitboard(b.pieceBitboards[colour|pieceType]).Count() - starting[pieceType]; extra > 0 {
+				promoted += extra
+			}
+		}
+		pawns := Bitboard(b.pieceBitboards[colour|PAWN]).Count()
+		if pawns+promoted > starting[PAWN] {
+			return &ValidationError{
+				Err:    ErrPieceCount,
+				Detail: fmt.Sprintf("%d pawns and %d promoted pieces for %s", pawns, promoted, name),
+			}
+		}
+	}
+	return nil
+}
+
+// mustValidate panics if the board fails Validate, saying what had just
+// been done to it.
+func mustValidate(b *Board, action string) {
+	if err := b.Validate(); err != nil {
+		panic(fmt.Sprintf("%s: %s\n%s", action, err, ToFEN(b)))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build boarddebug
+
+package board
+
+// validateMoves makes MakeMove and UndoMove check the board after every
+// move, as the boarddebug build tag is set.
+const validateMoves = true
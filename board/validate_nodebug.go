@@ -0,0 +1,7 @@
+//go:build !boarddebug
+
+package board
+
+// validateMoves is false unless built with the boarddebug tag, so the
+// checks are compiled out.
+const validateMoves = false
@@ -0,0 +1,72 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	forEachPerftPosition(t, func(b *Board) {
+		if err := b.Validate(); err != nil {
+			t.Fatalf("%s: %s", ToFEN(b), err)
+		}
+	})
+
+	tests := []struct {
+		fen     string
+		corrupt func(b *Board)
+		want    error
+	}{
+		{InitialPositionFEN, func(b *Board) { b.whiteKing = 0x05 }, ErrKingSquare},
+		{InitialPositionFEN, func(b *Board) { b.blackKing = 0x7F }, ErrKingSquare},
+		{InitialPositionFEN, func(b *Board) { b.squares[0x08] = WHITE | PAWN }, ErrOffBoard},
+		{InitialPositionFEN, func(b *Board) { b.squares[0x34] = WHITE | QUEEN }, ErrBitboards},
+		{InitialPositionFEN, func(b *Board) { b.pieceBitboards[WHITE|PAWN] = 0 }, ErrBitboards},
+		{InitialPositionFEN, func(b *Board) { b.removePiece(0x07); b.CalculateZobristHash() }, ErrFENCastlingRights},
+		{InitialPositionFEN, func(b *Board) { b.ep = 0x24; b.CalculateZobristHash() }, ErrFENEnPassant},
+		{InitialPositionFEN, func(b *Board) { b.zobristKey ^= 1 }, ErrHash},
+		{InitialPositionFEN, func(b *Board) { b.removePiece(0x74) }, ErrFENKingCount},
+		{"4k3/8/8/8/8/P7/PPPPPPPP/4K3 w - - 0 1", func(b *Board) {}, ErrPieceCount},
+		{"4k3/8/8/8/8/NNN5/PPPPPPPP/4K3 w - - 0 1", func(b *Board) {}, ErrPieceCount},
+		{"4k3/8/8/8/8/NNN5/PPPPPPP1/4K3 w - - 0 1", func(b *Board) {}, nil},
+		// The side not to move can be in check, as after LegalMove's trial moves.
+		{"4k3/4R3/8/8/8/8/8/4K3 w - - 0 1", func(b *Board) {}, nil},
+	}
+	for i, test := range tests {
+		b := FromFEN(test.fen)
+		test.corrupt(b)
+		if err := b.Validate(); !errors.Is(err, test.want) {
+			t.Errorf("Test %d: %s should give %v, not %v", i, test.fen, test.want, err)
+		}
+	}
+}
+
+// validatePerft checks the board after every move made and undone in a
+// perft run, so that the bitboards for each piece are known to stay in step
+// with the squares.
+func validatePerft(t *testing.T, b *Board, depth int) {
+	if depth == 0 {
+		return
+	}
+	for _, move := range GenerateLegalMoves(b) {
+		MakeMove(b, move)
+		if err := b.Validate(); err != nil {
+			t.Fatalf("After %s: %s", move, err)
+		}
+		validatePerft(t, b, depth-1)
+		UndoMove(b)
+		if err := b.Validate(); err != nil {
+			t.Fatalf("After undoing %s: %s", move, err)
+		}
+	}
+}
+
+func TestMakeUndoKeepsBoardValid(t *testing.T) {
+	depth := 3
+	if testing.Short() {
+		depth = 2
+	}
+	for _, entry := range readPerftSuite(t) {
+		validatePerft(t, FromFEN(entry.fen), depth)
+	}
+}
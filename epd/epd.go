@@ -0,0 +1,285 @@
+// Package epd reads and writes positions in Extended Position Description
+// format, as used by test suites.
+package epd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+// moveOpcodes are the opcodes whose operands are moves in SAN.
+var moveOpcodes = map[string]bool{
+	"am": true,
+	"bm": true,
+	"pm": true,
+	"pv": true,
+	"sm": true,
+}
+
+// sequenceOpcodes are move opcodes whose moves follow on from each other
+// rather than all being alternatives in the same position.
+var sequenceOpcodes = map[string]bool{
+	"pv": true,
+}
+
+// stringOpcodes are the opcodes whose operand is always a quoted string.
+var stringOpcodes = map[string]bool{
+	"id": true,
+	"c0": true, "c1": true, "c2": true, "c3": true, "c4": true,
+	"c5": true, "c6": true, "c7": true, "c8": true, "c9": true,
+}
+
+// Operation is an opcode with its operands. For opcodes taking moves, such
+// as bm and pv, Moves holds the operands resolved against the position.
+type Operation struct {
+	Opcode   string
+	Operands []string
+	Moves    []board.Move
+}
+
+// Int returns the first operand as an integer, e.g. for acd, ce or D1.
+func (op Operation) Int() (int, error) {
+	if len(op.Operands) == 0 {
+		return 0, fmt.Errorf("%s has no operand", op.Opcode)
+	}
+	return strconv.Atoi(op.Operands[0])
+}
+
+// Record is a single line of EPD.
+type Record struct {
+	Board      *board.Board
+	Operations []Operation
+}
+
+// Operation returns the operation with the given opcode.
+func (r *Record) Operation(opcode string) (Operation, bool) {
+	for _, op := range r.Operations {
+		if op.Opcode == opcode {
+			return op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// ID returns the operand of the id opcode, or an empty string.
+func (r *Record) ID() string {
+	if op, ok := r.Operation("id"); ok && len(op.Operands) > 0 {
+		return op.Operands[0]
+	}
+	return ""
+}
+
+// Parse parses a line of EPD. The half move clock and full move number are
+// taken from the hmvc and fmvn opcodes if present.
+func Parse(line string) (*Record, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, errors.New("EPD must have four position fields")
+	}
+	rest := line
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[len(fields[i]):]
+	}
+
+	operations, err := parseOperations(rest)
+	if err != nil {
+		return nil, err
+	}
+	r := &Record{Operations: operations}
+
+	fen := strings.Join(fields[:4], " ")
+	halfMove, fullMove := "0", "1"
+	if op, ok := r.Operation("hmvc"); ok && len(op.Operands) > 0 {
+		halfMove = op.Operands[0]
+	}
+	if op, ok := r.Operation("fmvn"); ok && len(op.Operands) > 0 {
+		fullMove = op.Operands[0]
+	}
+	r.Board, err = board.ParseFEN(fen + " " + halfMove + " " + fullMove)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range r.Operations {
+		if err := resolveMoves(r.Board, &r.Operations[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// parseOperations splits the operations following the position into
+// opcodes and operands. Each operation should end with a semicolon, but
+// the last one is accepted without.
+func parseOperations(s string) ([]Operation, error) {
+	var operations []Operation
+	var current *Operation
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		case c == ';':
+			if current == nil {
+				return nil, errors.New("missing opcode before ;")
+			}
+			current = nil
+		case c == '"':
+			if current == nil {
+				return nil, errors.New("string operand without opcode")
+			}
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated string operand")
+			}
+			current.Operands = append(current.Operands, s[i+1:i+1+end])
+			i += end + 1
+		default:
+			start := i
+			for i < len(s) && strings.IndexByte(" \t\r\n;", s[i]) < 0 {
+				i++
+			}
+			word := s[start:i]
+			i--
+			if current == nil {
+				operations = append(operations, Operation{Opcode: word})
+				current = &operations[len(operations)-1]
+			} else {
+				current.Operands = append(current.Operands, word)
+			}
+		}
+	}
+	return operations, nil
+}
+
+// resolveMoves fills in the moves for an operation taking SAN operands.
+func resolveMoves(b *board.Board, op *Operation) error {
+	if !moveOpcodes[op.Opcode] {
+		return nil
+	}
+	for _, san := range op.Operands {
+		m, err := board.ParseSAN(b, san)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op.Opcode, err)
+		}
+		op.Moves = append(op.Moves, m)
+		if sequenceOpcodes[op.Opcode] {
+			board.MakeMove(b, m)
+		}
+	}
+	if sequenceOpcodes[op.Opcode] {
+		for range op.Moves {
+			board.UndoMove(b)
+		}
+	}
+	return nil
+}
+
+// String returns the record as a line of EPD. Move operands are written in
+// SAN generated from Moves, if set.
+func (r *Record) String() string {
+	fen := strings.Fields(board.ToFEN(r.Board))
+	var result strings.Builder
+	result.WriteString(strings.Join(fen[:4], " "))
+
+	for _, op := range r.Operations {
+		result.WriteString(" " + op.Opcode)
+		operands := op.Operands
+		if moveOpcodes[op.Opcode] && len(op.Moves) > 0 {
+			operands = formatMoves(r.Board, op)
+		}
+		for _, operand := range operands {
+			if stringOpcodes[op.Opcode] || operand == "" || strings.ContainsAny(operand, " \t;\"") {
+				operand = "\"" + operand + "\""
+			}
+			result.WriteString(" " + operand)
+		}
+		result.WriteString(";")
+	}
+	return result.String()
+}
+
+func formatMoves(b *board.Board, op Operation) []string {
+	result := make([]string, len(op.Moves))
+	for i, m := range op.Moves {
+		result[i] = board.FormatSAN(b, m)
+		if sequenceOpcodes[op.Opcode] {
+			board.MakeMove(b, m)
+		}
+	}
+	if sequenceOpcodes[op.Opcode] {
+		for range op.Moves {
+			board.UndoMove(b)
+		}
+	}
+	return result
+}
+
+// Reader reads EPD records one per line, skipping blank lines.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader creates a Reader reading EPD from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{scanner: bufio.NewScanner(r)}
+}
+
+// Read returns the next record, or io.EOF if there are no more. A line that
+// can't be parsed gives a *LineError, and reading can continue after it.
+func (r *Reader) Read() (*Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+		record, err := Parse(text)
+		if err != nil {
+			return nil, &LineError{Line: r.line, Err: err}
+		}
+		return record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// LineError is returned by Reader for a line that could not be parsed.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Writer writes EPD records, one per line.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter creates a Writer writing EPD to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a record followed by a newline.
+func (w *Writer) Write(r *Record) error {
+	_, err := io.WriteString(w.w, r.String()+"\n")
+	return err
+}
@@ -0,0 +1,133 @@
+package epd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+func TestParse(t *testing.T) {
+	line := `r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - bm O-O Nxd7; am Qxh3; id "kiwi; pete"; c0 "a comment"; acd 12; ce -35; pv Nxd7 Kxd7 O-O; hmvc 3; fmvn 20;`
+	r, err := Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := board.ToFEN(r.Board); fen != "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 3 20" {
+		t.Errorf("Position not read correctly: %s", fen)
+	}
+	if r.ID() != "kiwi; pete" {
+		t.Errorf("id should be read, not %q", r.ID())
+	}
+	if op, _ := r.Operation("c0"); len(op.Operands) != 1 || op.Operands[0] != "a comment" {
+		t.Errorf("c0 not read correctly: %v", op)
+	}
+	bm, _ := r.Operation("bm")
+	if len(bm.Moves) != 2 || bm.Moves[0].String() != "e1g1" || bm.Moves[1].String() != "e5d7" {
+		t.Errorf("bm not resolved correctly: %v", bm.Moves)
+	}
+	am, _ := r.Operation("am")
+	if len(am.Moves) != 1 || am.Moves[0].String() != "f3h3" {
+		t.Errorf("am not resolved correctly: %v", am.Moves)
+	}
+	pv, _ := r.Operation("pv")
+	if len(pv.Moves) != 3 || pv.Moves[1].String() != "e8d7" || pv.Moves[2].String() != "e1g1" {
+		t.Errorf("pv not resolved correctly: %v", pv.Moves)
+	}
+	for opcode, value := range map[string]int{"acd": 12, "ce": -35} {
+		op, _ := r.Operation(opcode)
+		if n, err := op.Int(); err != nil || n != value {
+			t.Errorf("%s should be %d, not %d (%v)", opcode, value, n, err)
+		}
+	}
+
+	expected := `r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - bm O-O Nxd7; am Qxh3; id "kiwi; pete"; c0 "a comment"; acd 12; ce -35; pv Nxd7 Kxd7 O-O; hmvc 3; fmvn 20;`
+	if s := r.String(); s != expected {
+		t.Errorf("Output should be\n%s\nnot\n%s", expected, s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	invalid := map[string]error{
+		"4k3/8/8/8/8/8/8/4K3 w -":                   nil,
+		"4k3/8/8/8/8/8/8/4K3 w - - bm Ke2 \"x":      nil,
+		"4k3/8/8/8/8/8/8/4K3 w - - ; bm Kd2;":       nil,
+		"4k3/8/8/8/8/8/8/8 w - - bm Kd2;":           board.ErrFENKingCount,
+		"4k3/8/8/8/8/8/8/4K3 w - - bm Ke3;":         board.ErrSANIllegal,
+		"4k3/8/8/8/8/8/8/4K3 w - - pv Kd2 Kd2;":     board.ErrSANIllegal,
+		"4k3/8/8/8/8/8/8/4K3 w - - hmvc x; id \"\"": board.ErrFENClock,
+	}
+	for line, want := range invalid {
+		_, err := Parse(line)
+		if err == nil {
+			t.Errorf("%q should give an error", line)
+		} else if want != nil && !errors.Is(err, want) {
+			t.Errorf("%q should give %q, not %q", line, want, err)
+		}
+	}
+}
+
+func TestReaderWriter(t *testing.T) {
+	input := "4k3/8/8/8/8/8/8/4K2R w K - bm O-O; id \"1\";\n\n4k3/8/8/8/8/8/8/4K2R w X -\n4k3/8/8/8/8/8/8/R3K3 w Q - D1 16;\n"
+	r := NewReader(strings.NewReader(input))
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	var lineErr *LineError
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if errors.As(err, &lineErr) {
+			if lineErr.Line != 3 {
+				t.Errorf("Error should be on line 3, not %d", lineErr.Line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lineErr == nil {
+		t.Error("Invalid line should give a LineError")
+	}
+	expected := "4k3/8/8/8/8/8/8/4K2R w K - bm O-O; id \"1\";\n4k3/8/8/8/8/8/8/R3K3 w Q - D1 16;\n"
+	if out.String() != expected {
+		t.Errorf("Output should be\n%s\nnot\n%s", expected, out.String())
+	}
+}
+
+// The perft suite should be read without any errors.
+func TestPerftSuite(t *testing.T) {
+	f, err := os.Open("../perft/perftsuite.epd")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+	r := NewReader(f)
+	count := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		op, ok := record.Operation("D1")
+		if _, err := op.Int(); !ok || err != nil {
+			t.Errorf("D1 missing from %s", record)
+		}
+		count++
+	}
+	if count != 126 {
+		t.Errorf("Should be 126 records, not %d", count)
+	}
+}
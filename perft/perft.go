@@ -1,37 +1,52 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
-	"strconv"
-	"strings"
+	"os"
 	"time"
 
 	"github.com/micaherne/unidexter-go/board"
+	"github.com/micaherne/unidexter-go/epd"
 )
 
+var checkHash = flag.Bool("checkhash", false, "check the incremental hash against a full recalculation at every node")
+
 func main() {
+	flag.Parse()
 
-	suite := GetPerftsuite()
+	suite, err := GetPerftsuite()
+	if err != nil {
+		log.Fatal(err)
+	}
 	pass := 0
 	fail := 0
 	max := 6
-	for fen, counts := range suite {
+	for _, record := range suite {
 		start := time.Now()
-		fmt.Println("\n" + fen)
-		b := board.FromFEN(fen)
-		for i := 1; i <= max && i <= len(counts); i++ {
+		fmt.Println("\n" + board.ToFEN(record.Board))
+		b := record.Board
+		for i := 1; i <= max; i++ {
+			op, ok := record.Operation(fmt.Sprintf("D%d", i))
+			if !ok {
+				break
+			}
+			expected, err := op.Int()
+			if err != nil {
+				log.Fatalf("Invalid perft count for %s: %s", op.Opcode, err)
+			}
 			p := perft(b, i)
 
-			if p == counts[i-1] {
+			if p == expected {
 				pass++
 				fmt.Print("PASS. ")
 			} else {
 				fail++
 				fmt.Print("FAIL. ")
 			}
-			fmt.Printf("Perft(%d). Expected %d, got %d\n", i, counts[i-1], p)
+			fmt.Printf("Perft(%d). Expected %d, got %d\n", i, expected, p)
 		}
 		fmt.Printf("Time taken: %s", time.Since(start))
 	}
@@ -55,19 +70,27 @@ func (p Divide) String() string {
 }
 
 func perft(b *board.Board, depth int) int {
-	if depth == 0 {
+	return perftLists(b, make([]board.MoveList, depth))
+}
+
+// perftLists counts the leaf nodes to the depth given by the number of move
+// lists. Each ply has its own list so that nothing is allocated.
+func perftLists(b *board.Board, lists []board.MoveList) int {
+	if *checkHash && b.Hash() != b.FullZobristHash() {
+		log.Fatalf("Incremental hash %X differs from full hash %X in %s", b.Hash(), b.FullZobristHash(), board.ToFEN(b))
+	}
+	if len(lists) == 0 {
 		return 1
 	}
 
 	nodes := 0
 
-	moves := board.GenerateMoves(b)
-	for _, move := range moves {
-		if !board.LegalMove(b, move) {
-			continue
-		}
+	moves := &lists[0]
+	moves.Clear()
+	board.GenerateLegalMovesInto(b, moves)
+	for _, move := range moves.Moves() {
 		board.MakeMove(b, move)
-		nodes += perft(b, depth-1)
+		nodes += perftLists(b, lists[1:])
 		board.UndoMove(b)
 	}
 
@@ -81,11 +104,8 @@ func divide(b *board.Board, depth int) Divide {
 		return result
 	}
 
-	moves := board.GenerateMoves(b)
+	moves := board.GenerateLegalMoves(b)
 	for _, move := range moves {
-		if !board.LegalMove(b, move) {
-			continue
-		}
 		board.MakeMove(b, move)
 		nodes := perft(b, depth-1)
 		board.UndoMove(b)
@@ -96,33 +116,24 @@ func divide(b *board.Board, depth int) Divide {
 	return result
 }
 
-func GetPerftsuite() map[string][]int {
+func GetPerftsuite() ([]*epd.Record, error) {
 	file := "C:\\dev\\projects\\unidexter-go\\src\\github.com\\micaherne\\unidexter-go\\perft\\perftsuite.epd"
-	var content []byte
-	content, err := ioutil.ReadFile(file)
+	f, err := os.Open(file)
 	if err != nil {
-		// TODO: Do something
-		log.Println(err)
-		return make(map[string][]int, 0)
+		return nil, err
 	}
-	textLines := strings.Split(string(content[:]), "\n")
-	perfts := make(map[string][]int)
-	for _, line := range textLines {
-		if len(line) == 0 {
-			continue
+	defer f.Close()
+
+	var records []*epd.Record
+	r := epd.NewReader(f)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
 		}
-		parts := strings.Split(line, ";")
-		key := parts[0]
-		values := make([]int, len(parts)-1)
-		for j, result := range parts[1:] {
-			resParts := strings.Split(result, " ")
-			values[j], err = strconv.Atoi(resParts[1])
-			if err != nil {
-				// TODO: Report error
-			}
+		if err != nil {
+			return nil, err
 		}
-		perfts[key] = values
+		records = append(records, record)
 	}
-
-	return perfts
 }
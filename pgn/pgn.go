@@ -0,0 +1,118 @@
+// Package pgn reads and writes games in Portable Game Notation.
+package pgn
+
+import (
+	"fmt"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+// The possible game termination markers.
+const (
+	WhiteWins  = "1-0"
+	BlackWins  = "0-1"
+	Draw       = "1/2-1/2"
+	Unfinished = "*"
+)
+
+// sevenTagRoster is the set of tags that every exported game must have, in
+// the order they must be written.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Tag is a PGN tag pair.
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Move is a move in a game, along with any annotations and alternatives.
+type Move struct {
+	Move          board.Move
+	SAN           string // As found in the input. Not used by Writer.
+	NAGs          []int
+	CommentBefore string
+	CommentAfter  string
+	// Variations are alternative lines starting with a different move
+	// in place of this one.
+	Variations [][]*Move
+}
+
+// Game is a single game from a PGN file.
+type Game struct {
+	Tags   []Tag
+	Moves  []*Move
+	Result string
+	// Comment is a comment with no moves to attach to, e.g. in a game
+	// with no moves.
+	Comment string
+}
+
+// NewGame creates a game with the seven tag roster set to unknown values.
+func NewGame() *Game {
+	g := &Game{Result: Unfinished}
+	for _, name := range sevenTagRoster {
+		g.SetTag(name, "?")
+	}
+	g.SetTag("Date", "????.??.??")
+	g.SetTag("Result", Unfinished)
+	return g
+}
+
+// Tag returns the value of the named tag, or an empty string if the game
+// does not have it.
+func (g *Game) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// SetTag sets the value of a tag, adding it if it is not already present.
+func (g *Game) SetTag(name string, value string) {
+	for i, tag := range g.Tags {
+		if tag.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{name, value})
+}
+
+// StartingPosition returns the position the game starts from, which is the
+// one in the FEN tag if there is one.
+func (g *Game) StartingPosition() (*board.Board, error) {
+	if fen := g.Tag("FEN"); fen != "" {
+		return board.ParseFEN(fen)
+	}
+	return board.FromFEN(board.InitialPositionFEN), nil
+}
+
+// Board returns the position at the end of the main line.
+func (g *Game) Board() (*board.Board, error) {
+	b, err := g.StartingPosition()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range g.Moves {
+		board.MakeMove(b, m.Move)
+	}
+	return b, nil
+}
+
+// GameError is returned by Reader for a game that could not be read. The
+// reader skips to the next game, so reading can continue afterwards.
+type GameError struct {
+	Game int // Counting from 1.
+	Line int
+	Err  error
+}
+
+func (e *GameError) Error() string {
+	return fmt.Sprintf("game %d, line %d: %s", e.Game, e.Line, e.Err)
+}
+
+func (e *GameError) Unwrap() error {
+	return e.Err
+}
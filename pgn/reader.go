@@ -0,0 +1,391 @@
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+// suffixNAGs maps move suffix annotations to their numeric equivalents.
+var suffixNAGs = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// Reader reads a stream of games from PGN input.
+type Reader struct {
+	r       *bufio.Reader
+	line    int    // Lines read so far.
+	pending string // First line of the next game, if already read.
+	games   int
+}
+
+// NewReader creates a Reader reading PGN from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next game. It returns io.EOF when there are no more games.
+// If a game can't be parsed, Read returns as much of it as it could read
+// along with a *GameError, and the next call continues with the next game.
+func (r *Reader) Read() (*Game, error) {
+	text, startLine, err := r.readGameText()
+	if err != nil {
+		return nil, err
+	}
+	r.games++
+
+	p := &parser{tokens: tokenize(text, startLine)}
+	g, err := p.parseGame()
+	if err != nil {
+		return g, &GameError{Game: r.games, Line: p.line(), Err: err}
+	}
+	return g, nil
+}
+
+// readGameText reads the lines making up the next game. A game ends where
+// a tag pair starts after some movetext, or at the end of the input.
+func (r *Reader) readGameText() (string, int, error) {
+	var text strings.Builder
+	startLine := 0
+	inComment := false
+	sawMovetext := false
+	for {
+		var line string
+		if r.pending != "" {
+			line, r.pending = r.pending, ""
+		} else {
+			var err error
+			line, err = r.r.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return "", 0, err
+			}
+			if line == "" && err == io.EOF {
+				break
+			}
+			r.line++
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "%") || (trimmed == "" && text.Len() == 0) {
+			continue
+		}
+		isTag := !inComment && strings.HasPrefix(trimmed, "[")
+		if isTag && sawMovetext {
+			r.pending = line
+			break
+		}
+		if text.Len() == 0 {
+			startLine = r.line
+		}
+		text.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			text.WriteString("\n")
+		}
+		if isTag {
+			continue
+		}
+
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case inComment:
+				inComment = c != '}'
+			case c == '{':
+				inComment = true
+				sawMovetext = true
+			case c == ';':
+				sawMovetext = true
+				i = len(line)
+			case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+				sawMovetext = true
+			}
+		}
+	}
+
+	if text.Len() == 0 {
+		return "", 0, io.EOF
+	}
+	return text.String(), startLine, nil
+}
+
+type tokenKind int
+
+const (
+	tokenSymbol tokenKind = iota
+	tokenString
+	tokenPeriod
+	tokenAsterisk
+	tokenNAG
+	tokenSuffix
+	tokenComment
+	tokenOpenBracket
+	tokenCloseBracket
+	tokenOpenParen
+	tokenCloseParen
+	tokenInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func isSymbolStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isSymbolContinuation(c byte) bool {
+	return isSymbolStart(c) || strings.IndexByte("_+#=:-/", c) >= 0
+}
+
+// tokenize splits the text of a game into PGN tokens.
+func tokenize(text string, line int) []token {
+	var tokens []token
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		start := i
+		startLine := line
+		switch {
+		case c == '\n':
+			line++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			continue
+		case c == '{':
+			for i++; i < len(text) && text[i] != '}'; i++ {
+				if text[i] == '\n' {
+					line++
+				}
+			}
+			tokens = append(tokens, token{tokenComment, text[start+1 : i], startLine})
+		case c == ';':
+			for i++; i < len(text) && text[i] != '\n'; i++ {
+			}
+			tokens = append(tokens, token{tokenComment, text[start+1 : i], startLine})
+			i--
+		case c == '"':
+			var value strings.Builder
+			for i++; i < len(text) && text[i] != '"' && text[i] != '\n'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				value.WriteByte(text[i])
+			}
+			tokens = append(tokens, token{tokenString, value.String(), startLine})
+		case c == '$':
+			for i++; i < len(text) && text[i] >= '0' && text[i] <= '9'; i++ {
+			}
+			tokens = append(tokens, token{tokenNAG, text[start+1 : i], startLine})
+			i--
+		case c == '!' || c == '?':
+			for i++; i < len(text) && (text[i] == '!' || text[i] == '?'); i++ {
+			}
+			tokens = append(tokens, token{tokenSuffix, text[start:i], startLine})
+			i--
+		case isSymbolStart(c):
+			for i++; i < len(text) && isSymbolContinuation(text[i]); i++ {
+			}
+			tokens = append(tokens, token{tokenSymbol, text[start:i], startLine})
+			i--
+		default:
+			kinds := map[byte]tokenKind{
+				'.': tokenPeriod,
+				'*': tokenAsterisk,
+				'[': tokenOpenBracket,
+				']': tokenCloseBracket,
+				'(': tokenOpenParen,
+				')': tokenCloseParen,
+			}
+			kind, ok := kinds[c]
+			if !ok {
+				kind = tokenInvalid
+			}
+			tokens = append(tokens, token{kind, string(c), startLine})
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	game   *Game
+	b      *board.Board
+}
+
+// line returns the line number of the last token read.
+func (p *parser) line() int {
+	if p.pos > 0 {
+		return p.tokens[p.pos-1].line
+	}
+	if len(p.tokens) > 0 {
+		return p.tokens[0].line
+	}
+	return 0
+}
+
+func (p *parser) next() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	p.pos++
+	return p.tokens[p.pos-1], true
+}
+
+func (p *parser) parseGame() (*Game, error) {
+	p.game = &Game{}
+	if err := p.parseTags(); err != nil {
+		return p.game, err
+	}
+
+	var err error
+	p.b, err = p.game.StartingPosition()
+	if err != nil {
+		return p.game, err
+	}
+
+	p.game.Moves, err = p.parseLine(0)
+	if err == nil && p.game.Result == "" {
+		p.game.Result = p.game.Tag("Result")
+	}
+	return p.game, err
+}
+
+func (p *parser) parseTags() error {
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOpenBracket {
+		p.pos++
+		name, ok1 := p.next()
+		value, ok2 := p.next()
+		end, ok3 := p.next()
+		if !ok1 || !ok2 || !ok3 || name.kind != tokenSymbol || value.kind != tokenString || end.kind != tokenCloseBracket {
+			return errors.New("invalid tag pair")
+		}
+		p.game.SetTag(name.text, value.text)
+	}
+	return nil
+}
+
+// parseLine parses a sequence of moves, which is the main line if depth is
+// zero or a variation otherwise. The board is left as it was found.
+func (p *parser) parseLine(depth int) ([]*Move, error) {
+	var moves []*Move
+	defer func() {
+		for range moves {
+			board.UndoMove(p.b)
+		}
+	}()
+
+	var lastMove *Move
+	pendingComment := ""
+	for {
+		t, ok := p.next()
+		if !ok {
+			if depth > 0 {
+				return moves, errors.New("unterminated variation")
+			}
+			return moves, nil
+		}
+
+		switch t.kind {
+		case tokenPeriod:
+		case tokenAsterisk:
+			if depth > 0 {
+				return moves, errors.New("game termination marker in variation")
+			}
+			p.game.Result = Unfinished
+			return moves, p.checkEnd()
+		case tokenSymbol:
+			if t.text == WhiteWins || t.text == BlackWins || t.text == Draw {
+				if depth > 0 {
+					return moves, errors.New("game termination marker in variation")
+				}
+				p.game.Result = t.text
+				return moves, p.checkEnd()
+			}
+			if _, err := strconv.Atoi(t.text); err == nil {
+				// Move number
+				continue
+			}
+			m, err := board.ParseSAN(p.b, t.text)
+			if err != nil {
+				return moves, fmt.Errorf("move %d: %w", len(moves)+1, err)
+			}
+			board.MakeMove(p.b, m)
+			lastMove = &Move{Move: m, SAN: t.text, CommentBefore: pendingComment}
+			pendingComment = ""
+			moves = append(moves, lastMove)
+		case tokenNAG, tokenSuffix:
+			if lastMove == nil {
+				return moves, fmt.Errorf("annotation %s before first move", t.text)
+			}
+			nag, ok := suffixNAGs[t.text]
+			if t.kind == tokenNAG {
+				var err error
+				nag, err = strconv.Atoi(t.text)
+				ok = err == nil
+			}
+			if !ok {
+				return moves, fmt.Errorf("invalid annotation %s", t.text)
+			}
+			lastMove.NAGs = append(lastMove.NAGs, nag)
+		case tokenComment:
+			comment := strings.TrimSpace(t.text)
+			if lastMove == nil && depth == 0 {
+				p.game.Comment = joinComments(p.game.Comment, comment)
+			} else if lastMove == nil {
+				pendingComment = joinComments(pendingComment, comment)
+			} else {
+				lastMove.CommentAfter = joinComments(lastMove.CommentAfter, comment)
+			}
+		case tokenOpenParen:
+			if lastMove == nil {
+				return moves, errors.New("variation before first move")
+			}
+			board.UndoMove(p.b)
+			variation, err := p.parseLine(depth + 1)
+			board.MakeMove(p.b, lastMove.Move)
+			if err != nil {
+				return moves, err
+			}
+			lastMove.Variations = append(lastMove.Variations, variation)
+		case tokenCloseParen:
+			if depth == 0 {
+				return moves, errors.New("unexpected )")
+			}
+			if len(moves) == 0 {
+				return moves, errors.New("empty variation")
+			}
+			return moves, nil
+		default:
+			return moves, fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+}
+
+// checkEnd checks that nothing follows the game termination marker.
+func (p *parser) checkEnd() error {
+	for _, t := range p.tokens[p.pos:] {
+		if t.kind != tokenComment {
+			return fmt.Errorf("unexpected %q after game termination marker", t.text)
+		}
+	}
+	return nil
+}
+
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
@@ -0,0 +1,130 @@
+package pgn
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+const testPGN = `[Event "Test \"quoted\" event"]
+[Site "?"]
+[Date "2014.09.27"]
+[Round "1"]
+[White "Player, A"]
+[Black "Player, B"]
+[Result "1-0"]
+
+{Opening comment} 1. e4 e5 2. Nf3 Nc6 (2... Nf6 3. Nxe5 {Petroff} (3. d4) 3... d6) 3. Bb5! a6 $2
+4. Ba4 ; rest of line comment
+Nf6 5. O-O 1-0
+
+% Escaped line
+[Event "Illegal"]
+[Result "*"]
+
+1. e4 e5 2. Ke3 *
+
+[Event "From position"]
+[SetUp "1"]
+[FEN "4k3/P7/8/8/8/8/8/4K3 w - - 0 40"]
+[Result "1/2-1/2"]
+
+40. a8=Q+ Kd7 1/2-1/2
+`
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader(testPGN))
+
+	g, err := r.Read()
+	if err != nil {
+		t.Fatalf("Game 1 should be valid: %s", err)
+	}
+	if g.Tag("Event") != `Test "quoted" event` || g.Tag("White") != "Player, A" {
+		t.Errorf("Tags not read correctly: %v", g.Tags)
+	}
+	if g.Result != WhiteWins {
+		t.Errorf("Result should be 1-0, not %s", g.Result)
+	}
+	if g.Comment != "Opening comment" {
+		t.Errorf("Game comment should be read, not %q", g.Comment)
+	}
+	if len(g.Moves) != 9 {
+		t.Fatalf("Game 1 should have 9 moves, not %d", len(g.Moves))
+	}
+	if g.Moves[8].Move.String() != "e1g1" {
+		t.Errorf("Last move should be castling, not %s", g.Moves[8].Move)
+	}
+	if nags := g.Moves[4].NAGs; len(nags) != 1 || nags[0] != 1 {
+		t.Errorf("3. Bb5! should have NAG 1, not %v", nags)
+	}
+	if nags := g.Moves[5].NAGs; len(nags) != 1 || nags[0] != 2 {
+		t.Errorf("3... a6 should have NAG 2, not %v", nags)
+	}
+	if c := g.Moves[6].CommentAfter; c != "rest of line comment" {
+		t.Errorf("4. Ba4 should have a comment, not %q", c)
+	}
+	variations := g.Moves[3].Variations
+	if len(variations) != 1 || len(variations[0]) != 3 {
+		t.Fatalf("2... Nc6 should have one variation of 3 moves: %v", variations)
+	}
+	if variations[0][0].Move.String() != "g8f6" || variations[0][1].CommentAfter != "Petroff" {
+		t.Errorf("Variation not read correctly")
+	}
+	nested := variations[0][1].Variations
+	if len(nested) != 1 || nested[0][0].Move.String() != "d2d4" {
+		t.Errorf("Nested variation not read correctly")
+	}
+	b, err := g.Board()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := board.ToFEN(b); fen != "r1bqkb1r/1ppp1ppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 3 5" {
+		t.Errorf("Final position is wrong: %s", fen)
+	}
+
+	g, err = r.Read()
+	var gameErr *GameError
+	if !errors.As(err, &gameErr) {
+		t.Fatalf("Game 2 should give a GameError, not %v", err)
+	}
+	if gameErr.Game != 2 || gameErr.Line != 17 || !errors.Is(err, board.ErrSANIllegal) {
+		t.Errorf("Wrong error for game 2: %s", err)
+	}
+	if g == nil || g.Tag("Event") != "Illegal" || len(g.Moves) != 2 {
+		t.Errorf("Game 2 should be returned up to the illegal move")
+	}
+
+	g, err = r.Read()
+	if err != nil {
+		t.Fatalf("Game 3 should be valid: %s", err)
+	}
+	if g.Result != Draw || len(g.Moves) != 2 || g.Moves[0].Move.String() != "a7a8q" {
+		t.Errorf("Game 3 not read correctly")
+	}
+
+	if _, err = r.Read(); err != io.EOF {
+		t.Errorf("Should be EOF after the last game, not %v", err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	invalid := []string{
+		"[Event \"x\"\n\n1. e4 *",
+		"1. e4 (e5) *",
+		"1. e4 (1... e5 *",
+		"1. e4 e5) *",
+		"1. e4 e5 1-0 Nf3",
+		"$1 1. e4 *",
+		"1. e4 & *",
+		"[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n\n*",
+	}
+	for _, text := range invalid {
+		r := NewReader(strings.NewReader(text))
+		if _, err := r.Read(); err == nil {
+			t.Errorf("%q should give an error", text)
+		}
+	}
+}
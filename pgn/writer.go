@@ -0,0 +1,152 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+// maxLineLength is the longest movetext line written, as recommended for
+// export format.
+const maxLineLength = 79
+
+// Writer writes games in PGN export format.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter creates a Writer writing PGN to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes a game, followed by a blank line. The seven tag roster is
+// written first, and the moves are written in SAN generated from the
+// position rather than the SAN field of each move.
+func (w *Writer) Write(g *Game) error {
+	b, err := g.StartingPosition()
+	if err != nil {
+		return err
+	}
+
+	result := g.Result
+	if result == "" {
+		result = Unfinished
+	}
+
+	for _, name := range sevenTagRoster {
+		value := g.Tag(name)
+		if name == "Result" {
+			value = result
+		} else if value == "" {
+			value = "?"
+		}
+		w.writeTag(name, value)
+	}
+	for _, tag := range g.Tags {
+		if !isSevenTagRoster(tag.Name) {
+			w.writeTag(tag.Name, tag.Value)
+		}
+	}
+	w.w.WriteString("\n")
+
+	var tokens []string
+	if g.Comment != "" {
+		tokens = append(tokens, commentTokens(g.Comment)...)
+	}
+	tokens = appendLine(tokens, b, g.Moves, true)
+	tokens = append(tokens, result)
+	w.writeWrapped(tokens)
+	w.w.WriteString("\n")
+
+	return w.w.Flush()
+}
+
+func (w *Writer) writeTag(name, value string) {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	fmt.Fprintf(w.w, "[%s \"%s\"]\n", name, value)
+}
+
+// writeWrapped writes the tokens separated by spaces, starting a new line
+// whenever the next token would make the line too long.
+func (w *Writer) writeWrapped(tokens []string) {
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > maxLineLength {
+			w.w.WriteString("\n")
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			w.w.WriteString(" ")
+			lineLength++
+		}
+		w.w.WriteString(token)
+		lineLength += len(token)
+	}
+	w.w.WriteString("\n")
+}
+
+// appendLine appends the tokens for a line of moves starting from the
+// position given. The board is left as it was found.
+func appendLine(tokens []string, b *board.Board, moves []*Move, needMoveNumber bool) []string {
+	for _, m := range moves {
+		if m.CommentBefore != "" {
+			tokens = append(tokens, commentTokens(m.CommentBefore)...)
+			needMoveNumber = true
+		}
+		if board.ColourToMove(b) == board.WHITE {
+			tokens = append(tokens, fmt.Sprintf("%d.", b.FullMove()))
+		} else if needMoveNumber {
+			tokens = append(tokens, fmt.Sprintf("%d...", b.FullMove()))
+		}
+		needMoveNumber = false
+
+		tokens = append(tokens, board.FormatSAN(b, m.Move))
+		for _, nag := range m.NAGs {
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+		if m.CommentAfter != "" {
+			tokens = append(tokens, commentTokens(m.CommentAfter)...)
+			needMoveNumber = true
+		}
+		for _, variation := range m.Variations {
+			variationTokens := appendLine(nil, b, variation, true)
+			if len(variationTokens) == 0 {
+				continue
+			}
+			variationTokens[0] = "(" + variationTokens[0]
+			variationTokens[len(variationTokens)-1] += ")"
+			tokens = append(tokens, variationTokens...)
+			needMoveNumber = true
+		}
+		board.MakeMove(b, m.Move)
+	}
+	for range moves {
+		board.UndoMove(b)
+	}
+	return tokens
+}
+
+// commentTokens splits a comment into words so that it can be wrapped.
+func commentTokens(comment string) []string {
+	words := strings.Fields(strings.Replace(comment, "}", "", -1))
+	if len(words) == 0 {
+		return []string{"{}"}
+	}
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	return words
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, rosterName := range sevenTagRoster {
+		if name == rosterName {
+			return true
+		}
+	}
+	return false
+}
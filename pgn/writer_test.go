@@ -0,0 +1,97 @@
+package pgn
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/micaherne/unidexter-go/board"
+)
+
+func TestWriter(t *testing.T) {
+	g := NewGame()
+	g.SetTag("White", "Unidexter")
+	g.SetTag("Annotator", "A \"quoted\" name")
+	g.Result = BlackWins
+	b := board.FromFEN(board.InitialPositionFEN)
+	for _, san := range []string{"f3", "e5", "g4", "Qh4"} {
+		m, err := board.ParseSAN(b, san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		board.MakeMove(b, m)
+		g.Moves = append(g.Moves, &Move{Move: m})
+	}
+	g.Moves[2].NAGs = []int{4}
+	g.Moves[2].CommentAfter = "Oops"
+
+	var out bytes.Buffer
+	if err := NewWriter(&out).Write(g); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Unidexter"]
+[Black "?"]
+[Result "0-1"]
+[Annotator "A \"quoted\" name"]
+
+1. f3 e5 2. g4 $4 {Oops} 2... Qh4# 0-1
+
+`
+	if out.String() != expected {
+		t.Errorf("Output should be\n%s\nnot\n%s", expected, out.String())
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	r := NewReader(strings.NewReader(testPGN))
+	g, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	if err := w.Write(g); err != nil {
+		t.Fatal(err)
+	}
+	expectedMoves := `{Opening comment} 1. e4 e5 2. Nf3 Nc6 (2... Nf6 3. Nxe5 {Petroff} (3. d4) 3...
+d6) 3. Bb5 $1 a6 $2 4. Ba4 {rest of line comment} 4... Nf6 5. O-O 1-0
+`
+	if !strings.HasSuffix(out.String(), "\n"+expectedMoves+"\n") {
+		t.Errorf("Movetext should be\n%s\nin\n%s", expectedMoves, out.String())
+	}
+
+	for _, line := range strings.Split(out.String(), "\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("Line too long: %s", line)
+		}
+	}
+
+	g2, err := NewReader(&out).Read()
+	if err != nil {
+		t.Fatalf("Written game should be readable: %s", err)
+	}
+	b1, _ := g.Board()
+	b2, _ := g2.Board()
+	if board.ToFEN(b1) != board.ToFEN(b2) || len(g2.Moves[3].Variations) != 1 {
+		t.Errorf("Written game should read back the same")
+	}
+}
+
+func TestWriterFromPosition(t *testing.T) {
+	r := NewReader(strings.NewReader(testPGN))
+	var g *Game
+	for i := 0; i < 3; i++ {
+		g, _ = r.Read()
+	}
+	var out bytes.Buffer
+	if err := NewWriter(&out).Write(g); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\n40. a8=Q+ Kd7 1/2-1/2\n") {
+		t.Errorf("Moves should be numbered from the FEN: %s", out.String())
+	}
+}
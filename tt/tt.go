@@ -0,0 +1,220 @@
+// Package tt provides a transposition table, which remembers the results of
+// searching positions so that a search reaching the same position by a
+// different route, or at the next depth, can reuse them.
+//
+// Moves are stored as the 16 bit values of board.Move, as the board package
+// uses this one.
+package tt
+
+// Bound says how a stored score relates to the real score of a position.
+type Bound uint8
+
+const (
+	BoundNone  Bound = iota
+	BoundUpper       // The search failed low, so the score is at most this.
+	BoundLower       // The search failed high, so the score is at least this.
+	BoundExact
+)
+
+// Entry is the result of searching a position.
+type Entry struct {
+	Move  uint16
+	Score int
+	Depth int
+	Bound Bound
+}
+
+// entry is how an Entry is packed into the table. Only the top 16 bits of
+// the key are kept to check against. The low bits are implied by which
+// bucket it's in, but any bits between those and the top 16 are not
+// checked, so a different position can occasionally match.
+type entry struct {
+	key   uint16
+	move  uint16
+	score int16
+	depth int8
+	// The generation of the search that stored it in the top 6 bits, and
+	// the bound in the bottom 2.
+	genBound uint8
+}
+
+const (
+	entrySize     = 8
+	bucketEntries = 4
+	bucketSize    = entrySize * bucketEntries
+	generations   = 64
+)
+
+// bucket holds the entries for keys with the same low bits, so that a new
+// entry can replace whichever of them is least useful.
+type bucket [bucketEntries]entry
+
+// Table is a transposition table. It is not safe for concurrent use.
+type Table struct {
+	buckets       []bucket
+	mask          uint64
+	generation    uint8
+	mateThreshold int
+}
+
+// New creates a table using at most the given number of megabytes.
+//
+// The mate threshold is the smallest score, in either direction, which the
+// search uses for a mate rather than an evaluation. Mate scores count the
+// moves to mate from the root of the search, so the table stores them
+// counting from the position instead and turns them back when they are
+// probed at a different ply.
+func New(megabytes int, mateThreshold int) *Table {
+	t := &Table{mateThreshold: mateThreshold}
+	t.Resize(megabytes)
+	return t
+}
+
+// Resize changes the size of the table to at most the given number of
+// megabytes, rounded down to a power of two buckets, and clears it.
+func (t *Table) Resize(megabytes int) {
+	count := 1
+	for count*2*bucketSize <= megabytes<<20 {
+		count *= 2
+	}
+	t.buckets = make([]bucket, count)
+	t.mask = uint64(count - 1)
+	t.generation = 0
+}
+
+// Size returns the size of the table in bytes.
+func (t *Table) Size() int {
+	return len(t.buckets) * bucketSize
+}
+
+// Clear empties the table, e.g. before a new game.
+func (t *Table) Clear() {
+	for i := range t.buckets {
+		t.buckets[i] = bucket{}
+	}
+	t.generation = 0
+}
+
+// NewSearch tells the table a new search is starting, so that entries from
+// earlier searches are replaced before those from this one.
+func (t *Table) NewSearch() {
+	t.generation = (t.generation + 1) % generations
+}
+
+// Probe looks up a position by its hash key. The ply is how far the
+// position is from the root, for adjusting mate scores.
+func (t *Table) Probe(key uint64, ply int) (Entry, bool) {
+	b := &t.buckets[key&t.mask]
+	check := uint16(key >> 48)
+	for i := range b {
+		e := &b[i]
+		if e.key != check || e.bound() == BoundNone {
+			continue
+		}
+		// Keep the entry from being replaced, as it's still useful.
+		e.genBound = t.generation<<2 | uint8(e.bound())
+		return Entry{
+			Move:  e.move,
+			Score: t.scoreFromTable(int(e.score), ply),
+			Depth: int(e.depth),
+			Bound: e.bound(),
+		}, true
+	}
+	return Entry{}, false
+}
+
+// Store saves the result of searching a position to the given depth. A
+// move of 0 keeps any move already stored for the position.
+func (t *Table) Store(key uint64, move uint16, score int, depth int, bound Bound, ply int) {
+	b := &t.buckets[key&t.mask]
+	check := uint16(key >> 48)
+
+	// Use the entry for the same position if there is one, otherwise
+	// replace the one that is least useful: an empty one, or else the
+	// shallowest, counting entries from older searches as shallower.
+	replace := &b[0]
+	for i := range b {
+		e := &b[i]
+		if e.key == check || e.bound() == BoundNone {
+			replace = e
+			break
+		}
+		if t.worth(e) < t.worth(replace) {
+			replace = e
+		}
+	}
+
+	if replace.key == check && replace.bound() != BoundNone {
+		if move == 0 {
+			move = replace.move
+		}
+		// A shallower result for the same position is only worth keeping
+		// if it's exact.
+		if bound != BoundExact && depth < int(replace.depth)-2 && replace.generation() == t.generation {
+			replace.move = move
+			return
+		}
+	}
+
+	*replace = entry{
+		key:      check,
+		move:     move,
+		score:    int16(t.scoreToTable(score, ply)),
+		depth:    int8(depth),
+		genBound: t.generation<<2 | uint8(bound),
+	}
+}
+
+// Hashfull returns how full the table is in permille, as reported to UCI,
+// by sampling entries from the current search at the start of the table.
+func (t *Table) Hashfull() int {
+	sampled, used := 0, 0
+	for i := 0; i < len(t.buckets) && sampled < 1000; i++ {
+		for j := range t.buckets[i] {
+			e := &t.buckets[i][j]
+			sampled++
+			if e.bound() != BoundNone && e.generation() == t.generation {
+				used++
+			}
+		}
+	}
+	return used * 1000 / sampled
+}
+
+// worth is how much an entry is worth keeping, for choosing which entry to
+// replace.
+func (t *Table) worth(e *entry) int {
+	age := int(t.generation-e.generation()) % generations
+	return int(e.depth) - 8*age
+}
+
+func (e *entry) bound() Bound {
+	return Bound(e.genBound & 3)
+}
+
+func (e *entry) generation() uint8 {
+	return e.genBound >> 2
+}
+
+// scoreToTable turns a mate score counting from the root into one counting
+// from the position at the given ply.
+func (t *Table) scoreToTable(score int, ply int) int {
+	switch {
+	case score >= t.mateThreshold:
+		return score + ply
+	case score <= -t.mateThreshold:
+		return score - ply
+	}
+	return score
+}
+
+// scoreFromTable undoes scoreToTable for a position at the given ply.
+func (t *Table) scoreFromTable(score int, ply int) int {
+	switch {
+	case score >= t.mateThreshold:
+		return score - ply
+	case score <= -t.mateThreshold:
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,122 @@
+package tt
+
+import "testing"
+
+// mateThreshold is the mate threshold for the tests, which use mates
+// counting down from 30000.
+const mateThreshold = 29000
+
+func TestStoreAndProbe(t *testing.T) {
+	table := New(1, mateThreshold)
+	key := uint64(0x123456789ABCDEF0)
+	if _, ok := table.Probe(key, 0); ok {
+		t.Error("Empty table should not find anything")
+	}
+
+	table.Store(key, 0x1234, -57, 6, BoundLower, 3)
+	got, ok := table.Probe(key, 5)
+	if want := (Entry{Move: 0x1234, Score: -57, Depth: 6, Bound: BoundLower}); !ok || got != want {
+		t.Errorf("Probe should give %+v, not %+v", want, got)
+	}
+
+	// A key in the same bucket but with different top bits isn't a match.
+	if _, ok := table.Probe(key^1<<60, 0); ok {
+		t.Error("Probe should check the top bits of the key")
+	}
+
+	// Storing without a move keeps the move already there.
+	table.Store(key, 0, 12, 7, BoundExact, 0)
+	if got, _ := table.Probe(key, 0); got.Move != 0x1234 || got.Score != 12 {
+		t.Errorf("Move should be kept when storing without one, but got %+v", got)
+	}
+}
+
+func TestMateScores(t *testing.T) {
+	table := New(1, mateThreshold)
+	// Mate in 3 plies from a position 4 plies from the root is stored as
+	// mate in 3, so it is mate in 5 plies when found 2 plies from the root.
+	table.Store(1, 0, 30000-7, 5, BoundExact, 4)
+	if got, _ := table.Probe(1, 2); got.Score != 30000-5 {
+		t.Errorf("Mate score should be %d, not %d", 30000-5, got.Score)
+	}
+	table.Store(2, 0, -30000+8, 5, BoundExact, 4)
+	if got, _ := table.Probe(2, 6); got.Score != -30000+10 {
+		t.Errorf("Mated score should be %d, not %d", -30000+10, got.Score)
+	}
+	table.Store(3, 0, 500, 5, BoundExact, 4)
+	if got, _ := table.Probe(3, 6); got.Score != 500 {
+		t.Errorf("Other scores should not change, but 500 became %d", got.Score)
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	table := New(1, mateThreshold)
+	// Keys with the same low bits share a bucket.
+	key := func(i int) uint64 { return uint64(i+1) << 48 }
+	for i := 0; i < bucketEntries; i++ {
+		table.Store(key(i), uint16(i+1), 0, 10-i, BoundExact, 0)
+	}
+
+	// The shallowest entry is replaced first.
+	table.Store(key(4), 5, 0, 8, BoundExact, 0)
+	if _, ok := table.Probe(key(bucketEntries-1), 0); ok {
+		t.Error("Shallowest entry should have been replaced")
+	}
+	if _, ok := table.Probe(key(0), 0); !ok {
+		t.Error("Deepest entry should have been kept")
+	}
+
+	// Entries from older searches count as shallower, unless they have been
+	// probed since, so the two from the last search at depth 8 go first.
+	table.NewSearch()
+	table.Probe(key(0), 0)
+	table.Store(key(5), 6, 0, 1, BoundExact, 0)
+	table.Store(key(6), 7, 0, 1, BoundExact, 0)
+	for i, want := range map[int]bool{0: true, 1: true, 2: false, 4: false, 5: true, 6: true} {
+		if _, ok := table.Probe(key(i), 0); ok != want {
+			t.Errorf("Entry %d should be in the table: %t", i, want)
+		}
+	}
+
+	// A much shallower bound doesn't replace a deep result for the same
+	// position, but its move is kept.
+	table.Store(key(0), 9, 100, 2, BoundLower, 0)
+	if got, _ := table.Probe(key(0), 0); got.Depth != 10 || got.Move != 9 {
+		t.Errorf("Deep entry should be kept with the new move, not %+v", got)
+	}
+}
+
+func TestHashfullAndClear(t *testing.T) {
+	table := New(1, mateThreshold)
+	if table.Hashfull() != 0 {
+		t.Errorf("New table should be empty, not %d", table.Hashfull())
+	}
+	buckets := len(table.buckets)
+	for i := 0; i < buckets/2; i++ {
+		table.Store(uint64(i), 1, 0, 1, BoundExact, 0)
+	}
+	// A quarter of the entries, as there is one in each bucket filled.
+	if got := table.Hashfull(); got != 250 {
+		t.Errorf("Hashfull should be 250, not %d", got)
+	}
+	table.NewSearch()
+	if got := table.Hashfull(); got != 0 {
+		t.Errorf("Entries from the last search should not count, but hashfull is %d", got)
+	}
+	table.Clear()
+	if _, ok := table.Probe(0, 0); ok {
+		t.Error("Clear should empty the table")
+	}
+}
+
+func TestResize(t *testing.T) {
+	for _, test := range []struct{ megabytes, size int }{{0, bucketSize}, {1, 1 << 20}, {3, 2 << 20}, {16, 16 << 20}} {
+		table := New(test.megabytes, mateThreshold)
+		if table.Size() != test.size {
+			t.Errorf("Table of %dMB should be %d bytes, not %d", test.megabytes, test.size, table.Size())
+		}
+		if len(table.buckets)&(len(table.buckets)-1) != 0 {
+			t.Errorf("Table of %dMB should have a power of two buckets, not %d", test.megabytes, len(table.buckets))
+		}
+	}
+}
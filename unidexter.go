@@ -2,26 +2,41 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/micaherne/unidexter-go/board"
+	"github.com/micaherne/unidexter-go/tt"
 )
 
 var (
 	debug = false
 )
 
+// Limits of the Hash option, the size of the transposition table in MB.
+const (
+	defaultHash = 16
+	maxHash     = 1024
+)
+
 func main() {
 	var b *board.Board
+	table := tt.New(defaultHash, board.MateThreshold)
+	history := &board.History{}
 	reader := bufio.NewReader(os.Stdin)
 
 	board.InitZobristKeys()
 
 	go func(reader io.Reader) {
+		// stopSearch cancels the search running in the background, if
+		// there is one, and waits for it to send its best move.
+		stopSearch := func() {}
+
 		scanner := bufio.NewScanner(reader)
 		for scanner.Scan() {
 			text := scanner.Text()
@@ -38,7 +53,7 @@ func main() {
 				fmt.Println("id name Unidexter 0.0.1")
 				fmt.Println("id author Michael Aherne")
 
-				// TODO: option commands
+				fmt.Printf("option name Hash type spin default %d min 1 max %d\n", defaultHash, maxHash)
 				fmt.Println("uciok")
 			case "debug":
 				if commandParts[1] == "on" {
@@ -49,50 +64,106 @@ func main() {
 			case "isready":
 				fmt.Println("readyok")
 			case "setoption":
-				// TODO: Support options
+				var args []string
+				if len(commandParts) > 1 {
+					args = strings.Fields(commandParts[1])
+				}
+				// setoption name Hash value 64
+				if len(args) == 4 && args[0] == "name" && strings.EqualFold(args[1], "Hash") && args[2] == "value" {
+					size, err := strconv.Atoi(args[3])
+					if err != nil || size < 1 || size > maxHash {
+						fmt.Printf("info string invalid Hash size %q\n", args[3])
+						break
+					}
+					stopSearch()
+					table.Resize(size)
+				}
 			case "register":
 				// Not required
 			case "ucinewgame":
-				// TODO: Flush caches etc.
+				stopSearch()
+				table.Clear()
+				history.Clear()
 			case "position":
-				// TODO: Error checking
 				var fen string
 				var moves []string
-				positionParts := strings.Split(commandParts[1], " ")
-				if positionParts[0] == "startpos" {
-					fen = board.InitialPositionFEN
-					if len(positionParts) > 1 && positionParts[1] == "moves" {
-						moves = positionParts[2:]
-					}
+				var positionParts []string
+				if len(commandParts) > 1 {
+					positionParts = strings.Fields(commandParts[1])
 				}
 				for i, part := range positionParts {
 					if part == "moves" {
-						if fen == "" {
-							fen = strings.Join(positionParts[:i-1], " ")
-						}
 						moves = positionParts[i+1:]
+						positionParts = positionParts[:i]
 						break
 					}
 				}
-				if fen == "" {
-					fen = strings.Join(positionParts, " ")
+				if len(positionParts) > 0 && positionParts[0] == "startpos" {
+					fen = board.InitialPositionFEN
+				} else if len(positionParts) > 0 && positionParts[0] == "fen" {
+					fen = strings.Join(positionParts[1:], " ")
 				}
 
-				b = board.FromFEN(fen)
+				newBoard, err := board.ParseFEN(fen)
+				if err != nil {
+					fmt.Printf("info string %s\n", err)
+					break
+				}
+				// Keep the last position unless all of the moves are
+				// legal, rather than leaving it part way through them.
+				valid := true
 				for _, move := range moves {
-					board.MakeMoveFromNotation(b, move)
+					if err := board.MakeMoveFromNotation(newBoard, move); err != nil {
+						fmt.Printf("info string %s\n", err)
+						valid = false
+						break
+					}
+				}
+				if valid {
+					b = newBoard
 				}
 
 			case "go":
-				// TODO: Start calculating
-				bestMove := board.NegamaxAlphaBeta(b, 5)
-				fmt.Printf("bestmove %s\n", bestMove)
-				// TODO: What if we don't find a decent move?
+				var args []string
+				if len(commandParts) > 1 {
+					args = strings.Fields(commandParts[1])
+				}
+				limits, err := board.ParseSearchLimits(args)
+				if err != nil {
+					fmt.Printf("info string %s\n", err)
+					break
+				}
+				if b == nil {
+					fmt.Println("info string no position to search")
+					break
+				}
+				stopSearch()
+
+				// Search a copy in the background, so that stop and
+				// isready can be answered while it runs.
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func(b *board.Board) {
+					defer close(done)
+					bestMove := board.Search(ctx, b, table, history, limits, printInfo)
+					// An infinite search waits for stop before answering,
+					// even if it has finished.
+					if limits.Infinite {
+						<-ctx.Done()
+					}
+					fmt.Printf("bestmove %s\n", bestMove)
+				}(b.Clone())
+				stopSearch = func() {
+					cancel()
+					<-done
+				}
 			case "stop":
-				// TODO: Stop calculating
+				stopSearch()
+				stopSearch = func() {}
 			case "ponderhit":
 				// TODO: Start calculating expected move
 			case "quit":
+				stopSearch()
 				os.Exit(0)
 			}
 		}
@@ -103,3 +174,17 @@ func main() {
 	}
 
 }
+
+// printInfo sends the result of a depth of the search to the GUI.
+func printInfo(info board.SearchInfo) {
+	score := fmt.Sprintf("cp %d", info.Score)
+	if info.Mate != 0 {
+		score = fmt.Sprintf("mate %d", info.Mate)
+	}
+	nps := 0
+	if info.Time > 0 {
+		nps = int(float64(info.Nodes) / info.Time.Seconds())
+	}
+	fmt.Printf("info depth %d score %s nodes %d nps %d hashfull %d time %d pv %s\n",
+		info.Depth, score, info.Nodes, nps, info.Hashfull, info.Time.Milliseconds(), info.Move)
+}